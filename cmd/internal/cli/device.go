@@ -0,0 +1,116 @@
+package cli
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	flag "github.com/spf13/pflag"
+	"go.uber.org/zap"
+
+	"usbscreen/pkg/bitmap"
+	"usbscreen/pkg/device"
+	"usbscreen/pkg/device/multi"
+	"usbscreen/pkg/proto"
+)
+
+// DeviceFlags are the flags of the commands drawing on a device
+type DeviceFlags struct {
+	serial       *string
+	grid         *string
+	readTimeout  *time.Duration
+	writeTimeout *time.Duration
+	reconnect    *int
+	ackWait      *time.Duration
+	chunk        *int
+	rate         *int
+	profile      *string
+	remoteToken  *string
+	remoteCA     *string
+	remoteCert   *string
+	remoteKey    *string
+}
+
+// NewDeviceFlags registers the device flags on the command line
+func NewDeviceFlags() *DeviceFlags {
+	return &DeviceFlags{
+		serial:       flag.String("serial", "ttyACM0", "device spec, e.g. ttyACM0, inch35:usb:VID:PID, remote://host:9123, mock:// or screen:///dump/dir"),
+		grid:         flag.String("grid", "", "stitch comma separated device specs into cols x rows grid, e.g. 2x1"),
+		readTimeout:  flag.Duration("read-timeout", 0, "serial read timeout"),
+		writeTimeout: flag.Duration("write-timeout", 0, "serial write timeout"),
+		reconnect:    flag.Int("reconnect", 5, "serial reopen attempts"),
+		ackWait:      flag.Duration("ack-wait", 0, "wait for panel response after every write"),
+		chunk:        flag.Int("chunk", 0, "bitmap upload chunk size in bytes"),
+		rate:         flag.Int("rate", 0, "bitmap upload rate limit in bytes per second"),
+		profile:      flag.String("profile", "", "color calibration profile file"),
+		remoteToken:  flag.String("remote-token", "", "remote server token"),
+		remoteCA:     flag.String("remote-ca", "", "remote server CA file, enables TLS"),
+		remoteCert:   flag.String("remote-cert", "", "client certificate file for remote server"),
+		remoteKey:    flag.String("remote-key", "", "client private key file for remote server"),
+	}
+}
+
+// Open opens the device of the flags, calibrated with the profile if set
+func (f *DeviceFlags) Open(logger *zap.Logger) (proto.Control, error) {
+	spec := *f.serial
+	if *f.grid != "" {
+		spec = multi.Spec(*f.grid, strings.Split(*f.serial, ","))
+	}
+
+	dev, err := device.Open(spec, logger, f.defaults())
+	if err != nil || *f.profile == "" {
+		return dev, err
+	}
+
+	p, err := bitmap.LoadProfile(*f.profile)
+	if err != nil {
+		_ = device.Close(dev)
+		return nil, err
+	}
+
+	calibrated, err := device.Calibrate(dev, p)
+	if err != nil {
+		_ = device.Close(dev)
+		return nil, err
+	}
+	return calibrated, nil
+}
+
+// LightFlag is --light in percent as the wallhaven bot takes it, 100 is the brightest
+type LightFlag struct {
+	percent *uint8
+}
+
+// NewLightFlag registers the light flag on the command line
+func NewLightFlag() *LightFlag {
+	return &LightFlag{
+		percent: flag.Uint8("light", 50, "screen light in percent, 100 is the brightest"),
+	}
+}
+
+// Percent is the flag as given
+func (f *LightFlag) Percent() uint8 {
+	return *f.percent
+}
+
+// Level is the device light level of the flag, 0 is the brightest
+func (f *LightFlag) Level() uint8 {
+	return proto.LightLevel(*f.percent)
+}
+
+// defaults passes the flags to drivers, they are overridden by the device spec query
+func (f *DeviceFlags) defaults() url.Values {
+	return url.Values{
+		"read-timeout":  {f.readTimeout.String()},
+		"write-timeout": {f.writeTimeout.String()},
+		"reconnect":     {strconv.Itoa(*f.reconnect)},
+		"ack-wait":      {f.ackWait.String()},
+		"chunk":         {strconv.Itoa(*f.chunk)},
+		"rate":          {strconv.Itoa(*f.rate)},
+		"token":         {*f.remoteToken},
+		"ca":            {*f.remoteCA},
+		"cert":          {*f.remoteCert},
+		"key":           {*f.remoteKey},
+	}
+}
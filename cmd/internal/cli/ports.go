@@ -0,0 +1,20 @@
+package cli
+
+import (
+	"fmt"
+	"log"
+
+	"usbscreen/pkg/proto"
+)
+
+// PrintPorts lists the serial ports with their usb selectors for --list-ports
+func PrintPorts() {
+	ports, err := proto.ListPorts()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	for _, p := range ports {
+		fmt.Println(p)
+	}
+}
@@ -3,34 +3,56 @@ package main
 import (
 	"net/http"
 
+	"github.com/pkg/errors"
 	flag "github.com/spf13/pflag"
 	"go.uber.org/fx"
 	"go.uber.org/zap"
 
-	"usbscreen/pkg/device/inch35"
+	"usbscreen/cmd/internal/cli"
+	_ "usbscreen/pkg/device/drivers"
 	"usbscreen/pkg/device/remote"
-	"usbscreen/pkg/proto"
 )
 
-var serial = flag.String("serial", "ttyACM0", "serial name")
+var devFlags = cli.NewDeviceFlags()
+var listPorts = flag.Bool("list-ports", false, "list serial ports and exit")
 var listen = flag.String("listen", ":9123", "listen addr")
+var tlsCert = flag.String("tls-cert", "", "server certificate file, enables TLS")
+var tlsKey = flag.String("tls-key", "", "server private key file")
+var tlsClientCA = flag.String("tls-client-ca", "", "require client certificates signed by this CA")
+var tokens = flag.StringSlice("tokens", nil, "allowed client tokens")
 
 func main() {
 	flag.Parse()
 
+	if *listPorts {
+		cli.PrintPorts()
+		return
+	}
+
 	fx.New(
 		fx.Provide(
-			func() *proto.Serial {
-				return proto.NewSerial(*serial)
+			func() (*http.Server, error) {
+				srv := &http.Server{Addr: *listen}
+				if *tlsCert == "" && (*tlsKey != "" || *tlsClientCA != "") {
+					return nil, errors.New("--tls-key and --tls-client-ca need --tls-cert")
+				}
+				if *tlsCert != "" {
+					cfg, err := remote.ServerTLS(*tlsCert, *tlsKey, *tlsClientCA)
+					if err != nil {
+						return nil, err
+					}
+					srv.TLSConfig = cfg
+				}
+				return srv, nil
 			},
-			func() *http.Server {
-				return &http.Server{Addr: *listen}
+			func() *remote.Auth {
+				return &remote.Auth{Tokens: *tokens}
 			},
 			func() *zap.Logger {
 				l, _ := zap.NewDevelopment()
 				return l
 			},
-			inch35.New,
+			devFlags.Open,
 		),
 		fx.Invoke(
 			remote.Proxy,
@@ -0,0 +1,228 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"os"
+	"time"
+
+	"github.com/inhies/go-bytesize"
+
+	"usbscreen/pkg/mixer"
+	"usbscreen/pkg/text"
+)
+
+const margin = 8
+
+var (
+	colorTrack  = color.RGBA{0x30, 0x30, 0x30, 0xFF}
+	colorHeader = color.RGBA{0x18, 0x18, 0x28, 0xFF}
+)
+
+// gauge is a row of the dashboard, a title and value line over a bar and a sparkline
+type gauge struct {
+	layer   *mixer.Layer
+	title   string
+	color   color.RGBA
+	size    image.Point
+	history []float64
+}
+
+func (g *gauge) push(v float64) {
+	g.history = append(g.history, v)
+	if n := g.size.X / 2; len(g.history) > n {
+		g.history = g.history[len(g.history)-n:]
+	}
+}
+
+// draw renders the row, frac fills the bar and is skipped if negative,
+// the sparkline is scaled to peak
+func (g *gauge) draw(r *text.Renderer, value string, frac float64, peak float64) {
+	img := image.NewRGBA(image.Rectangle{Max: g.size})
+
+	title := r.Render(g.title)
+	draw.Draw(img, title.Bounds(), title, image.Point{}, draw.Over)
+	label := r.Render(value)
+	lb := label.Bounds()
+	draw.Draw(img, lb.Add(image.Pt(g.size.X-lb.Dx(), 0)), label, image.Point{}, draw.Over)
+
+	y := title.Bounds().Dy() + 2
+	if frac >= 0 {
+		bar := image.Rect(0, y, g.size.X, y+6)
+		draw.Draw(img, bar, image.NewUniform(colorTrack), image.Point{}, draw.Src)
+		bar.Max.X = int(float64(g.size.X) * clamp(frac))
+		draw.Draw(img, bar, image.NewUniform(g.color), image.Point{}, draw.Src)
+		y += 10
+	}
+
+	sparkline(img, image.Rect(0, y, g.size.X, g.size.Y), g.history, peak, g.color)
+	g.layer.SetImage(img)
+}
+
+// sparkline fills a column for every value from the right edge, the newest last
+func sparkline(img *image.RGBA, r image.Rectangle, values []float64, peak float64, c color.RGBA) {
+	if r.Dy() <= 0 || peak <= 0 {
+		return
+	}
+
+	dim := color.RGBA{c.R / 3, c.G / 3, c.B / 3, 0xFF}
+	for i := range values {
+		x := r.Max.X - (len(values)-i)*2
+		if x < r.Min.X {
+			continue
+		}
+		h := int(float64(r.Dy()) * clamp(values[i]/peak))
+		col := image.Rect(x, r.Max.Y-h, x+2, r.Max.Y)
+		draw.Draw(img, col, image.NewUniform(dim), image.Point{}, draw.Src)
+		if h > 0 {
+			draw.Draw(img, image.Rect(x, col.Min.Y, x+2, col.Min.Y+1), image.NewUniform(c), image.Point{}, draw.Src)
+		}
+	}
+}
+
+func newDashboard(scene *mixer.Scene, fonts *text.Fonts, size image.Point) (*dashboard, error) {
+	headerH := size.Y / 12
+	if headerH < 24 {
+		headerH = 24
+	}
+
+	titles := []struct {
+		name  string
+		color color.RGBA
+	}{
+		{"CPU", color.RGBA{0x4C, 0xAF, 0x50, 0xFF}},
+		{"MEM", color.RGBA{0x21, 0x96, 0xF3, 0xFF}},
+		{"DISK", color.RGBA{0xFF, 0x98, 0x00, 0xFF}},
+		{"NET", color.RGBA{0xAB, 0x47, 0xBC, 0xFF}},
+		{"TEMP", color.RGBA{0xF4, 0x43, 0x36, 0xFF}},
+	}
+
+	rowH := (size.Y - headerH - margin) / len(titles)
+	fontSize := float64(rowH) / 5
+	if fontSize < 12 {
+		fontSize = 12
+	} else if fontSize > 24 {
+		fontSize = 24
+	}
+
+	label, err := text.NewRenderer(fonts, text.WithSize(fontSize))
+	if err != nil {
+		return nil, err
+	}
+	header, err := text.NewRenderer(fonts, text.WithSize(float64(headerH)*0.6))
+	if err != nil {
+		return nil, err
+	}
+
+	d := &dashboard{
+		label:   label,
+		header:  header,
+		size:    size,
+		headerH: headerH,
+		top:     scene.Layer("header", 10).Move(image.Pt(margin, 0)),
+	}
+	d.host, _ = os.Hostname()
+
+	for i, t := range titles {
+		d.gauges = append(d.gauges, &gauge{
+			layer: scene.Layer(t.name, 0).Move(image.Pt(margin, headerH+margin+i*rowH)),
+			title: t.name,
+			color: t.color,
+			size:  image.Pt(size.X-margin*2, rowH-margin),
+		})
+	}
+
+	return d, nil
+}
+
+type dashboard struct {
+	label   *text.Renderer
+	header  *text.Renderer
+	size    image.Point
+	headerH int
+	host    string
+	top     *mixer.Layer
+	gauges  []*gauge
+}
+
+func (d *dashboard) update(s Sample, now time.Time) {
+	bar := image.NewRGBA(image.Rect(0, 0, d.size.X-margin*2, d.headerH))
+	draw.Draw(bar, bar.Bounds(), image.NewUniform(colorHeader), image.Point{}, draw.Src)
+	host := d.header.Render(d.host)
+	hb := host.Bounds()
+	draw.Draw(bar, hb.Add(image.Pt(4, (d.headerH-hb.Dy())/2)), host, image.Point{}, draw.Over)
+	clock := d.header.Render(now.Format("15:04:05"))
+	cb := clock.Bounds()
+	draw.Draw(bar, cb.Add(image.Pt(bar.Bounds().Dx()-cb.Dx()-4, (d.headerH-cb.Dy())/2)), clock, image.Point{}, draw.Over)
+	d.top.SetImage(bar)
+
+	cpu, mem, disk, net, temp := d.gauges[0], d.gauges[1], d.gauges[2], d.gauges[3], d.gauges[4]
+
+	// the parts missing in the sample keep their history
+	if s.HasCPU {
+		cpu.push(s.CPU)
+		cpu.draw(d.label, fmt.Sprintf("%.0f%%", s.CPU*100), s.CPU, 1)
+	} else {
+		cpu.draw(d.label, "n/a", -1, 1)
+	}
+
+	if s.HasMem {
+		memFrac := ratio(s.MemUsed, s.MemTotal)
+		mem.push(memFrac)
+		mem.draw(d.label, usage(s.MemUsed, s.MemTotal), memFrac, 1)
+	} else {
+		mem.draw(d.label, "n/a", -1, 1)
+	}
+
+	if s.HasDisk {
+		diskFrac := ratio(s.DiskUsed, s.DiskTotal)
+		disk.push(diskFrac)
+		disk.draw(d.label, usage(s.DiskUsed, s.DiskTotal), diskFrac, 1)
+	} else {
+		disk.draw(d.label, "n/a", -1, 1)
+	}
+
+	if s.HasNet {
+		net.push(s.RxRate + s.TxRate)
+	}
+	peak := 1024.0
+	for _, v := range net.history {
+		if v > peak {
+			peak = v
+		}
+	}
+	if s.HasNet {
+		net.draw(d.label, fmt.Sprintf("rx %s/s tx %s/s", bytesize.New(s.RxRate), bytesize.New(s.TxRate)), -1, peak)
+	} else {
+		net.draw(d.label, "n/a", -1, peak)
+	}
+
+	if s.HasTemp {
+		temp.push(s.Temp)
+		temp.draw(d.label, fmt.Sprintf("%.1f°C", s.Temp), s.Temp/100, 100)
+	} else {
+		temp.draw(d.label, "n/a", -1, 100)
+	}
+}
+
+func usage(used, total uint64) string {
+	return fmt.Sprintf("%s / %s", bytesize.New(float64(used)), bytesize.New(float64(total)))
+}
+
+func ratio(used, total uint64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(used) / float64(total)
+}
+
+func clamp(v float64) float64 {
+	if v < 0 {
+		return 0
+	} else if v > 1 {
+		return 1
+	}
+	return v
+}
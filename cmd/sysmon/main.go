@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"image"
+	"log"
+	"os/signal"
+	"syscall"
+	"time"
+
+	flag "github.com/spf13/pflag"
+	"go.uber.org/zap"
+
+	"usbscreen/cmd/internal/cli"
+	"usbscreen/pkg/bitmap"
+	_ "usbscreen/pkg/device/drivers"
+	"usbscreen/pkg/mixer"
+	"usbscreen/pkg/proto"
+	"usbscreen/pkg/text"
+)
+
+var devFlags = cli.NewDeviceFlags()
+var light = cli.NewLightFlag()
+var landscape = flag.Bool("landscape", false, "set landscape")
+var invert = flag.Bool("invert", false, "set invert")
+var interval = flag.Duration("interval", time.Second, "update interval")
+var drawTimeout = flag.Duration("draw-timeout", 10*time.Second, "give up a frame after, 0 means never")
+var disk = flag.String("disk", "/", "path of the filesystem shown as disk")
+var fonts = flag.StringArray("font", nil, "TTF, OTF or TTC font files, tried in order before the builtin one")
+var dither = flag.String("dither", "none", "dithering to the panel format, none, floyd-steinberg or bayer")
+
+func main() {
+	flag.Parse()
+
+	ditherMode, dErr := bitmap.ParseDither(*dither)
+	if dErr != nil {
+		log.Fatal(dErr)
+	}
+
+	logger, _ := zap.NewDevelopment()
+
+	fs, fErr := text.LoadFonts(*fonts...)
+	if errors.Is(fErr, text.ErrNoCJKFont) {
+		logger.Warn(fErr.Error())
+	} else if fErr != nil {
+		log.Fatal(fErr)
+	}
+
+	dev, devErr := devFlags.Open(logger)
+	if devErr != nil {
+		log.Fatal(devErr)
+	}
+
+	if err := dev.Startup(); err != nil {
+		log.Fatal(err)
+	}
+
+	if err := dev.SetRotate(*landscape, *invert); err != nil {
+		log.Fatal(err)
+	}
+
+	if err := dev.SetLight(light.Level()); err != nil {
+		log.Fatal(err)
+	}
+
+	state, sErr := dev.State()
+	if sErr != nil {
+		log.Fatal(sErr)
+	}
+
+	size := image.Pt(state.Width, state.Height)
+	scene := mixer.NewScene(mixer.NewDrawer(dev, mixer.WithDither(ditherMode)), size)
+
+	dash, dashErr := newDashboard(scene, fs, size)
+	if dashErr != nil {
+		log.Fatal(dashErr)
+	}
+
+	// cancelled on exit, so a hung device does not block the shutdown
+	root, stop := signal.NotifyContext(context.Background(), syscall.SIGHUP, syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	smp := newSampler(*disk)
+	ticker := time.NewTicker(*interval)
+	defer ticker.Stop()
+
+	for {
+		s, err := smp.sample()
+		if err != nil {
+			logger.With(zap.Error(err)).Info("sample incomplete")
+		}
+		dash.update(s, time.Now())
+		if err := render(root, scene); err != nil && root.Err() == nil {
+			logger.With(zap.Error(err)).Info("render failed")
+		}
+
+		select {
+		case <-root.Done():
+			logger.Info("shutting down")
+
+			ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+			defer cancel()
+			if err := proto.WithContext(dev).ShutdownContext(ctx); err != nil {
+				logger.With(zap.Error(err)).Info("shutdown failed")
+			}
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// render gives up a frame after the draw timeout, the next one covers it
+func render(ctx context.Context, scene *mixer.Scene) error {
+	if *drawTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *drawTimeout)
+		defer cancel()
+	}
+	return scene.RenderContext(ctx)
+}
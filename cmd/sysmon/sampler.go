@@ -0,0 +1,92 @@
+package main
+
+import (
+	"time"
+
+	"go.uber.org/multierr"
+)
+
+// counters are the raw values, rates are taken between two of them
+type counters struct {
+	cpuIdle  uint64
+	cpuTotal uint64
+	rx, tx   uint64
+	netAt    time.Time
+}
+
+// Sample holds what could be read, the Has fields tell which values are valid
+type Sample struct {
+	// CPU is the busy part in 0-1 since the last sample
+	CPU       float64
+	HasCPU    bool
+	MemUsed   uint64
+	MemTotal  uint64
+	HasMem    bool
+	DiskUsed  uint64
+	DiskTotal uint64
+	HasDisk   bool
+	// RxRate and TxRate are bytes per second of all interfaces but loopback
+	RxRate  float64
+	TxRate  float64
+	HasNet  bool
+	Temp    float64
+	HasTemp bool
+}
+
+func newSampler(disk string) *sampler {
+	return &sampler{disk: disk}
+}
+
+type sampler struct {
+	disk string
+	last counters
+}
+
+// sample reads the current values, the rates are zero for the first one. A part failing
+// is left out of the sample, the error tells all of them.
+func (s *sampler) sample() (Sample, error) {
+	var smp Sample
+	var errs error
+
+	if idle, total, err := readCPU(); err != nil {
+		errs = multierr.Append(errs, err)
+	} else {
+		if d := total - s.last.cpuTotal; s.last.cpuTotal > 0 && d > 0 {
+			smp.CPU = 1 - float64(idle-s.last.cpuIdle)/float64(d)
+		}
+		s.last.cpuIdle, s.last.cpuTotal = idle, total
+		smp.HasCPU = true
+	}
+
+	if rx, tx, err := readNet(); err != nil {
+		errs = multierr.Append(errs, err)
+	} else {
+		now := time.Now()
+		if secs := now.Sub(s.last.netAt).Seconds(); !s.last.netAt.IsZero() && secs > 0 {
+			// counters going back after an interface reset are ignored
+			if rx >= s.last.rx {
+				smp.RxRate = float64(rx-s.last.rx) / secs
+			}
+			if tx >= s.last.tx {
+				smp.TxRate = float64(tx-s.last.tx) / secs
+			}
+		}
+		s.last.rx, s.last.tx, s.last.netAt = rx, tx, now
+		smp.HasNet = true
+	}
+
+	if total, avail, err := readMemory(); err != nil {
+		errs = multierr.Append(errs, err)
+	} else {
+		smp.MemTotal, smp.MemUsed, smp.HasMem = total, total-avail, true
+	}
+
+	if used, total, err := readDisk(s.disk); err != nil {
+		errs = multierr.Append(errs, err)
+	} else {
+		smp.DiskUsed, smp.DiskTotal, smp.HasDisk = used, total, true
+	}
+
+	smp.Temp, smp.HasTemp = readTemperature()
+	return smp, errs
+}
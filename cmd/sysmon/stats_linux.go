@@ -0,0 +1,126 @@
+package main
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/pkg/errors"
+)
+
+// readCPU returns the idle and total jiffies of all cpus
+func readCPU() (uint64, uint64, error) {
+	f, err := os.Open("/proc/stat")
+	if err != nil {
+		return 0, 0, err
+	}
+	defer f.Close()
+
+	var idle, total uint64
+	s := bufio.NewScanner(f)
+	if !s.Scan() || !strings.HasPrefix(s.Text(), "cpu ") {
+		return 0, 0, errors.New("invalid /proc/stat")
+	}
+	for i, field := range strings.Fields(s.Text())[1:] {
+		v, _ := strconv.ParseUint(field, 10, 64)
+		// idle and iowait
+		if i == 3 || i == 4 {
+			idle += v
+		}
+		// guest times are already part of user and nice
+		if i < 8 {
+			total += v
+		}
+	}
+	return idle, total, nil
+}
+
+// readNet returns the received and sent bytes of all interfaces but loopback
+func readNet() (uint64, uint64, error) {
+	bs, err := os.ReadFile("/proc/net/dev")
+	if err != nil {
+		return 0, 0, err
+	}
+
+	var rx, tx uint64
+	for _, line := range strings.Split(string(bs), "\n") {
+		name, stats, ok := strings.Cut(line, ":")
+		if !ok || strings.TrimSpace(name) == "lo" {
+			continue
+		}
+		fields := strings.Fields(stats)
+		if len(fields) < 9 {
+			continue
+		}
+		r, _ := strconv.ParseUint(fields[0], 10, 64)
+		t, _ := strconv.ParseUint(fields[8], 10, 64)
+		rx += r
+		tx += t
+	}
+	return rx, tx, nil
+}
+
+// readMemory returns the total and available bytes
+func readMemory() (uint64, uint64, error) {
+	bs, err := os.ReadFile("/proc/meminfo")
+	if err != nil {
+		return 0, 0, err
+	}
+
+	var total, avail uint64
+	for _, line := range strings.Split(string(bs), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		v, _ := strconv.ParseUint(fields[1], 10, 64)
+		switch fields[0] {
+		case "MemTotal:":
+			total = v * 1024
+		case "MemAvailable:":
+			avail = v * 1024
+		}
+	}
+
+	if total == 0 {
+		return 0, 0, errors.New("invalid /proc/meminfo")
+	}
+	return total, avail, nil
+}
+
+// readDisk returns the used and total bytes of the filesystem at path as df does,
+// the blocks reserved for root are neither used nor part of the total
+func readDisk(path string) (uint64, uint64, error) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(path, &st); err != nil {
+		return 0, 0, errors.Wrapf(err, "statfs %s", path)
+	}
+	used := (st.Blocks - st.Bfree) * uint64(st.Bsize)
+	return used, used + st.Bavail*uint64(st.Bsize), nil
+}
+
+// readTemperature returns the hottest thermal zone or hwmon sensor in celsius
+func readTemperature() (float64, bool) {
+	files, _ := filepath.Glob("/sys/class/thermal/thermal_zone*/temp")
+	hwmon, _ := filepath.Glob("/sys/class/hwmon/hwmon*/temp*_input")
+
+	var max float64
+	var found bool
+	for _, file := range append(files, hwmon...) {
+		bs, err := os.ReadFile(file)
+		if err != nil {
+			continue
+		}
+		v, err := strconv.ParseFloat(strings.TrimSpace(string(bs)), 64)
+		if err != nil {
+			continue
+		}
+		if t := v / 1000; !found || t > max {
+			max, found = t, true
+		}
+	}
+	return max, found
+}
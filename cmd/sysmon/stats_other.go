@@ -0,0 +1,27 @@
+//go:build !linux
+
+package main
+
+import "github.com/pkg/errors"
+
+var errUnsupported = errors.New("sysmon reads /proc and /sys, only linux is supported")
+
+func readCPU() (uint64, uint64, error) {
+	return 0, 0, errUnsupported
+}
+
+func readNet() (uint64, uint64, error) {
+	return 0, 0, errUnsupported
+}
+
+func readMemory() (uint64, uint64, error) {
+	return 0, 0, errUnsupported
+}
+
+func readDisk(path string) (uint64, uint64, error) {
+	return 0, 0, errUnsupported
+}
+
+func readTemperature() (float64, bool) {
+	return 0, false
+}
@@ -1,6 +1,10 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"image"
+	"image/color"
 	"log"
 	"os"
 	"os/signal"
@@ -13,19 +17,27 @@ import (
 	flag "github.com/spf13/pflag"
 	"go.uber.org/zap"
 
+	"usbscreen/cmd/internal/cli"
 	"usbscreen/pkg/album"
-	"usbscreen/pkg/device/inch35"
-	"usbscreen/pkg/device/remote"
-	"usbscreen/pkg/device/virtual"
+	"usbscreen/pkg/bitmap"
+	_ "usbscreen/pkg/device/drivers"
 	"usbscreen/pkg/mixer"
 	"usbscreen/pkg/proto"
+	"usbscreen/pkg/text"
 )
 
-var serial = flag.String("serial", "ttyACM0", "serial name or remote addr")
-var light = flag.Uint8("light", 50, "set light")
+var devFlags = cli.NewDeviceFlags()
+var listPorts = flag.Bool("list-ports", false, "list serial ports and exit")
+var drawTimeout = flag.Duration("draw-timeout", 2*time.Minute, "give up a drawing after, 0 means never")
+var light = cli.NewLightFlag()
 var landscape = flag.Bool("landscape", false, "set landscape")
 var invert = flag.Bool("invert", false, "set invert")
 var interval = flag.String("interval", "5m", "draw interval")
+var effect = flag.StringArray("effect", nil, "transition effects picked randomly, name[:size=N,speed=D,dir=up,steps=N]")
+var dither = flag.String("dither", "none", "dithering to the panel format, none, floyd-steinberg or bayer")
+var caption = flag.String("caption", "none", "caption on wallpapers, none, resolution or tags")
+var captionSize = flag.Float64("caption-size", 14, "caption font size in pixels")
+var fonts = flag.StringArray("font", nil, "TTF, OTF or TTC font files for captions, tried in order before the builtin one")
 var debug = flag.Bool("debug", false, "set debug")
 var whKey = flag.String("wh-key", "", "wallhaven api key")
 var whQuery = flag.String("wh-query", "", "wallhaven query string")
@@ -49,13 +61,28 @@ var autoSaveFIncDaily = flag.Int("auto-save-f-inc-daily", -1, "auto save if favo
 func main() {
 	flag.Parse()
 
-	p := album.NewParams(320, 480)
-	p.ScreenLight = *light
+	if *listPorts {
+		cli.PrintPorts()
+		return
+	}
 
-	if d, err := time.ParseDuration(*interval); err != nil {
-		log.Fatal(err)
-	} else {
-		p.ChangeWait = d
+	changeWait, wErr := time.ParseDuration(*interval)
+	if wErr != nil {
+		log.Fatal(wErr)
+	}
+
+	ditherMode, dErr := bitmap.ParseDither(*dither)
+	if dErr != nil {
+		log.Fatal(dErr)
+	}
+
+	var effects []mixer.Effect
+	for _, spec := range *effect {
+		eff, err := mixer.ParseEffect(spec)
+		if err != nil {
+			log.Fatal(err)
+		}
+		effects = append(effects, eff)
 	}
 
 	bSize, bErr := bytesize.Parse(*maxSize)
@@ -64,6 +91,7 @@ func main() {
 	}
 
 	logger, _ := zap.NewDevelopment()
+	captionFn := captionFunc(logger)
 
 	tmp, tErr := album.NewTmpFs(*tmpDir)
 	if tErr != nil {
@@ -80,17 +108,7 @@ func main() {
 		log.Fatal(dErr)
 	}
 
-	var dev proto.Control
-	var devErr error
-
-	if *serial == "mock" {
-		dev = virtual.Mock(logger)
-	} else if strings.Contains(*serial, ":") {
-		dev, devErr = remote.New(*serial)
-	} else {
-		dev, devErr = inch35.New(proto.NewSerial(*serial), logger)
-	}
-
+	dev, devErr := devFlags.Open(logger)
 	if devErr != nil {
 		log.Fatal(devErr)
 	}
@@ -99,16 +117,21 @@ func main() {
 		log.Fatal(err)
 	}
 
-	if err := dev.SetLight(p.GetLight()); err != nil {
+	if err := dev.SetRotate(*landscape, *invert); err != nil {
 		log.Fatal(err)
 	}
 
-	if err := dev.SetRotate(*landscape, *invert); err != nil {
-		log.Fatal(err)
+	state, sErr := dev.State()
+	if sErr != nil {
+		log.Fatal(sErr)
 	}
 
-	if *landscape {
-		p.SwapRatio()
+	p := album.NewParams(state.Width, state.Height)
+	p.ScreenLight = light.Percent()
+	p.ChangeWait = changeWait
+
+	if err := dev.SetLight(p.GetLight()); err != nil {
+		log.Fatal(err)
 	}
 
 	wh := api.New(*whKey)
@@ -140,12 +163,34 @@ func main() {
 		p.SetQuery(q)
 	}
 
-	mix := mixer.NewDrawer(dev, mixer.WithEffect(
-	//mixer.EffectBlock(),
-	))
+	mix := mixer.NewDrawer(dev,
+		mixer.WithEffect(effects...),
+		mixer.WithDither(ditherMode),
+	)
+
+	var drawerOpts []album.DrawerOption
+	if captionFn != nil {
+		fs, err := text.LoadFonts(*fonts...)
+		if errors.Is(err, text.ErrNoCJKFont) {
+			logger.Warn(err.Error())
+		} else if err != nil {
+			log.Fatal(err)
+		}
+		r, err := text.NewRenderer(fs,
+			text.WithSize(*captionSize),
+			text.WithWidth(state.Width*9/10),
+			text.WithAlign(text.AlignCenter),
+			text.WithOutline(1, color.Black),
+			text.WithShadow(image.Pt(1, 1), color.RGBA{A: 0x80}),
+		)
+		if err != nil {
+			log.Fatal(err)
+		}
+		drawerOpts = append(drawerOpts, album.WithCaption(r, captionFn, 4))
+	}
 
 	history := album.NewHistory()
-	drawer := album.NewDrawer(mix, p, tmp, cache, history, logger)
+	drawer := album.NewDrawer(mix, p, tmp, cache, history, logger, drawerOpts...)
 
 	var bot *album.Bot
 	if *tgToken != "" {
@@ -166,16 +211,22 @@ func main() {
 	shutdown := make(chan struct{})
 	exited := make(chan struct{})
 
+	// cancelled on exit, so a hung device does not block the shutdown
+	root, stop := context.WithCancel(context.Background())
+	defer stop()
+
+	albumOpts := []album.Option{
+		album.WithMaxPage(*maxPage),
+		album.WithMaxSize(int(bSize)),
+		album.WithAutoSave(logger, *autoSaveViews, *autoSaveFavorites, *autoSaveFIncDaily),
+	}
+
 	go func() {
 		timer := time.NewTimer(time.Nanosecond)
 		wakeupChan := p.WakeupChan()
 		resetChan := p.ResetChan()
 
-		ab := album.New(p, downloader, drawer,
-			album.WithMaxPage(*maxPage),
-			album.WithMaxSize(int(bSize)),
-			album.WithAutoSave(logger, *autoSaveViews, *autoSaveFavorites, *autoSaveFIncDaily),
-		)
+		ab := album.New(p, downloader, drawer, albumOpts...)
 
 		defer func() {
 			timer.Stop()
@@ -185,7 +236,9 @@ func main() {
 				bot.Stop()
 			}
 
-			if err := dev.Shutdown(); err != nil {
+			ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+			defer cancel()
+			if err := proto.WithContext(dev).ShutdownContext(ctx); err != nil {
 				logger.With(zap.Error(err)).Info("shutdown failed")
 			}
 
@@ -207,7 +260,7 @@ func main() {
 					logger.Info("switch paused, skip...")
 					continue
 				}
-				if err := ab.Drawing(); err != nil {
+				if err := drawing(root, ab); err != nil {
 					logger.With(zap.Error(err)).Info("drawing failed")
 					timer.Reset(p.ErrorWait)
 				} else {
@@ -237,6 +290,30 @@ func main() {
 		}
 	}()
 
+	stop()
 	shutdown <- struct{}{}
 	<-wait
 }
+
+func drawing(ctx context.Context, ab *album.Album) error {
+	if *drawTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *drawTimeout)
+		defer cancel()
+	}
+	return ab.Drawing(ctx)
+}
+
+// captionFunc returns the caption chosen by the flag, nil for none
+func captionFunc(logger *zap.Logger) album.Caption {
+	switch *caption {
+	case "", "none":
+		return nil
+	case "resolution":
+		return album.CaptionResolution
+	case "tags":
+		return album.CaptionTags(*whKey, logger)
+	}
+	log.Fatalf("unknown caption %q", *caption)
+	return nil
+}
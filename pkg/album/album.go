@@ -1,6 +1,7 @@
 package album
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"image"
@@ -38,7 +39,7 @@ type Album struct {
 	autoSave *autoSave
 }
 
-func (a *Album) pickImage() (*api.Wallpaper, image.Image, error) {
+func (a *Album) pickImage(ctx context.Context) (*api.Wallpaper, image.Image, error) {
 	wp, err := a.params.GetResult().Pick(api.PickLoop)
 	if err != nil {
 		if errors.Is(err, api.ErrNoMoreItems) {
@@ -54,7 +55,8 @@ func (a *Album) pickImage() (*api.Wallpaper, image.Image, error) {
 	autoSave := a.autoSave != nil && a.autoSave.Check(wp)
 	useThumb := !autoSave && (a.maxSize > 0 && wp.FileSize > a.maxSize)
 
-	filled, err2 := a.d.Filled(
+	filled, err2 := a.d.FilledContext(
+		ctx,
 		wp,
 		// fetcher
 		func(wp *api.Wallpaper) (*VFile, bool, error) {
@@ -85,16 +87,17 @@ func (a *Album) pickImage() (*api.Wallpaper, image.Image, error) {
 	return wp, filled, err2
 }
 
-func (a *Album) Drawing() error {
+// Drawing picks the next wallpaper and draws it, the drawing stops once ctx is done
+func (a *Album) Drawing(ctx context.Context) error {
 	if !a.d.TryLock() {
 		return errors.New("drawer busying")
 	}
 	defer a.d.Unlock()
 
-	_, filled, err := a.pickImage()
+	_, filled, err := a.pickImage(ctx)
 
 	if filled != nil {
-		if err := a.d.Canvas(filled); err != nil {
+		if err := a.d.CanvasContext(ctx, filled); err != nil {
 			return fmt.Errorf("draw bitmap failed: %w", err)
 		}
 	}
@@ -5,10 +5,21 @@ import (
 
 	"github.com/moolex/wallhaven-go/api"
 	"go.uber.org/zap"
+
+	"usbscreen/pkg/text"
 )
 
 type Option func(a *Album)
 
+type DrawerOption func(d *Drawer)
+
+// WithCaption renders fn of every wallpaper at its bottom with r, gap pixels above the edge
+func WithCaption(r *text.Renderer, fn Caption, gap int) DrawerOption {
+	return func(d *Drawer) {
+		d.caption = &caption{r: r, fn: fn, gap: gap}
+	}
+}
+
 func WithMaxPage(max int) Option {
 	return func(a *Album) {
 		a.maxPage = max
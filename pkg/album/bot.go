@@ -13,6 +13,7 @@ import (
 	"github.com/samber/lo"
 	tele "gopkg.in/telebot.v3"
 
+	"usbscreen/pkg/mixer"
 	"usbscreen/pkg/proto"
 )
 
@@ -112,7 +113,11 @@ func (b *Bot) handleConfig() {
 	b.b.Handle("/light", func(context tele.Context) error {
 		in := context.Message().Payload
 		if in == "" {
-			return context.Reply(strconv.Itoa(int(b.params.ScreenLight)))
+			state, err := b.dev.State()
+			if err != nil {
+				return context.Reply(fmt.Sprintf("get state failed: %s", err))
+			}
+			return context.Reply(b.lightText(state))
 		}
 
 		if parsed, err := strconv.ParseUint(in, 10, 8); err == nil {
@@ -125,6 +130,59 @@ func (b *Bot) handleConfig() {
 
 		return context.Reply("OK")
 	})
+
+	b.b.Handle("/effect", func(context tele.Context) error {
+		in := strings.Fields(context.Message().Payload)
+		if len(in) == 0 {
+			names := lo.Map(b.d.mixer.Effects(), func(e mixer.Effect, _ int) string { return e.Name() })
+			return context.Reply(fmt.Sprintf("Using: %s\nAvailable: %s",
+				lo.Ternary(len(names) > 0, strings.Join(names, ", "), "none"),
+				strings.Join(mixer.Effects(), ", "),
+			))
+		}
+
+		var effs []mixer.Effect
+		if in[0] != "none" {
+			for _, spec := range in {
+				eff, err := mixer.ParseEffect(spec)
+				if err != nil {
+					return context.Reply(fmt.Sprintf("change failed: %s", err))
+				}
+				effs = append(effs, eff)
+			}
+		}
+
+		b.d.mixer.SetEffects(effs...)
+		return context.Reply("OK")
+	})
+}
+
+func (b *Bot) handleState() {
+	b.b.Handle("/state", func(context tele.Context) error {
+		state, err := b.dev.State()
+		if err != nil {
+			return context.Reply(fmt.Sprintf("get state failed: %s", err))
+		}
+
+		lines := []string{
+			fmt.Sprintf("Powered: %t", state.Powered),
+			fmt.Sprintf("Light: %s", b.lightText(state)),
+			fmt.Sprintf("Resolution: %dx%d", state.Width, state.Height),
+			fmt.Sprintf("Landscape: %t", state.Landscape),
+			fmt.Sprintf("Invert: %t", state.Invert),
+			fmt.Sprintf("Mirror: %t", state.Mirror),
+		}
+
+		return context.Reply(strings.Join(lines, "\n"))
+	})
+}
+
+// lightText is the light of state in percent, a panel doesn't know it until it's set
+func (b *Bot) lightText(state *proto.State) string {
+	if state.Light == nil {
+		return "unknown"
+	}
+	return strconv.Itoa(int(b.params.LightPercent(*state.Light)))
 }
 
 func (b *Bot) handleQuery() {
@@ -242,6 +300,7 @@ func (b *Bot) handleAction() {
 			return context.Reply("Previous no item")
 		}
 
+		b.d.Abort()
 		if err := b.d.Canvas(log.filled); err != nil {
 			return context.Reply(fmt.Sprintf("draw canvas failed: %s", err))
 		}
@@ -279,6 +338,7 @@ func (b *Bot) handleAction() {
 			return context.Reply(fmt.Sprintf("get thumb failed: %s", err))
 		}
 
+		b.d.Abort()
 		if err := b.d.Canvas(filled); err != nil {
 			return context.Reply(fmt.Sprintf("draw canvas failed: %s", err))
 		}
@@ -305,6 +365,7 @@ func (b *Bot) handleAction() {
 func (b *Bot) Start() {
 	b.handleBase()
 	b.handleConfig()
+	b.handleState()
 	b.handleQuery()
 	b.handleAction()
 	go b.b.Start()
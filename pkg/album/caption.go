@@ -0,0 +1,83 @@
+package album
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"image/draw"
+	"strings"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/moolex/wallhaven-go/api"
+	"go.uber.org/zap"
+
+	"usbscreen/pkg/text"
+)
+
+// Caption is the text put on a wallpaper, empty for none. ctx is the one of the draw,
+// the drawer is locked meanwhile.
+type Caption func(ctx context.Context, wp *api.Wallpaper) string
+
+func CaptionResolution(_ context.Context, wp *api.Wallpaper) string {
+	return wp.Resolution
+}
+
+// tagsTimeout bounds fetching the tags, the wallpaper is drawn without them after
+const tagsTimeout = 10 * time.Second
+
+// CaptionTags joins the tag names. Search results come without them, they are
+// fetched from the wallpaper details with key and kept on the wallpaper.
+func CaptionTags(key string, logger *zap.Logger) Caption {
+	cli := resty.New().
+		SetBaseURL("https://wallhaven.cc/api/v1").
+		SetQueryParam("apikey", key).
+		SetTimeout(tagsTimeout)
+
+	return func(ctx context.Context, wp *api.Wallpaper) string {
+		if len(wp.Tags) == 0 {
+			var detail struct {
+				Data api.Wallpaper `json:"data"`
+			}
+			resp, err := cli.R().SetContext(ctx).SetResult(&detail).Get("/w/" + wp.Id)
+			if err == nil && resp.IsError() {
+				err = fmt.Errorf("server responded %s", resp.Status())
+			}
+			if err != nil {
+				logger.With(zap.String("id", wp.Id), zap.Error(err)).Info("fetch tags failed")
+				return ""
+			}
+			wp.Tags = detail.Data.Tags
+		}
+
+		names := make([]string, 0, len(wp.Tags))
+		for _, tag := range wp.Tags {
+			names = append(names, tag.Name)
+		}
+		return strings.Join(names, ", ")
+	}
+}
+
+type caption struct {
+	r   *text.Renderer
+	fn  Caption
+	gap int
+}
+
+// draw returns img with the caption blended at the bottom, img itself is kept
+func (c *caption) draw(ctx context.Context, wp *api.Wallpaper, img image.Image) image.Image {
+	s := c.fn(ctx, wp)
+	if s == "" {
+		return img
+	}
+
+	b := img.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(dst, dst.Bounds(), img, b.Min, draw.Src)
+
+	label := c.r.Render(s)
+	lb := label.Bounds()
+	at := image.Pt((b.Dx()-lb.Dx())/2, b.Dy()-lb.Dy()-c.gap)
+	draw.Draw(dst, lb.Add(at), label, image.Point{}, draw.Over)
+	return dst
+}
@@ -2,6 +2,7 @@ package album
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"image"
@@ -16,10 +17,11 @@ import (
 	"go.uber.org/zap"
 
 	"usbscreen/pkg/mixer"
+	"usbscreen/pkg/proto"
 )
 
-func NewDrawer(mixer *mixer.Drawer, params *Params, tmp *TmpFs, cache *Cache, history *History, logger *zap.Logger) *Drawer {
-	return &Drawer{
+func NewDrawer(mixer *mixer.Drawer, params *Params, tmp *TmpFs, cache *Cache, history *History, logger *zap.Logger, opts ...DrawerOption) *Drawer {
+	d := &Drawer{
 		mixer:   mixer,
 		params:  params,
 		tmpfs:   tmp,
@@ -27,6 +29,12 @@ func NewDrawer(mixer *mixer.Drawer, params *Params, tmp *TmpFs, cache *Cache, hi
 		history: history,
 		logger:  logger,
 	}
+
+	for _, opt := range opts {
+		opt(d)
+	}
+
+	return d
 }
 
 type Drawer struct {
@@ -37,6 +45,11 @@ type Drawer struct {
 	cache   *Cache
 	history *History
 	logger  *zap.Logger
+	// options
+	caption *caption
+
+	cl     sync.Mutex
+	cancel context.CancelFunc
 }
 
 type wpFetcher func(wp *api.Wallpaper) (origin *VFile, thumb bool, err error)
@@ -44,6 +57,11 @@ type preCache func(wp *api.Wallpaper, thumb image.Image) error
 type postFetch func(wp *api.Wallpaper, thumb bool, origin *VFile) error
 
 func (d *Drawer) Filled(wp *api.Wallpaper, fetcher wpFetcher, preCache preCache, postFetch postFetch) (image.Image, error) {
+	return d.FilledContext(context.Background(), wp, fetcher, preCache, postFetch)
+}
+
+// FilledContext is Filled with the caption fetched under ctx
+func (d *Drawer) FilledContext(ctx context.Context, wp *api.Wallpaper, fetcher wpFetcher, preCache preCache, postFetch postFetch) (image.Image, error) {
 	exists, cacheImg, errL := d.cache.LoadImage(wp, d.params.width, d.params.height)
 	if errL != nil {
 		return nil, fmt.Errorf("load cache failed: %w", errL)
@@ -55,8 +73,9 @@ func (d *Drawer) Filled(wp *api.Wallpaper, fetcher wpFetcher, preCache preCache,
 				return nil, fmt.Errorf("cache handler failed: %w", err)
 			}
 		}
-		d.history.Add(wp, cacheImg, false, nil)
-		return cacheImg, nil
+		captioned := d.captioned(ctx, wp, cacheImg)
+		d.history.Add(wp, captioned, false, nil)
+		return captioned, nil
 	}
 
 	origin, thumb, errG := fetcher(wp)
@@ -81,8 +100,17 @@ func (d *Drawer) Filled(wp *api.Wallpaper, fetcher wpFetcher, preCache preCache,
 		}
 	}
 
-	d.history.Add(wp, filled, thumb, origin)
-	return filled, nil
+	// the cache keeps the plain image, the history what was drawn
+	captioned := d.captioned(ctx, wp, filled)
+	d.history.Add(wp, captioned, thumb, origin)
+	return captioned, nil
+}
+
+func (d *Drawer) captioned(ctx context.Context, wp *api.Wallpaper, img image.Image) image.Image {
+	if d.caption == nil {
+		return img
+	}
+	return d.caption.draw(ctx, wp, img)
 }
 
 func (d *Drawer) byLocal(vf *VFile) (image.Image, error) {
@@ -139,5 +167,32 @@ func (d *Drawer) byIMagick(vf *VFile) (image.Image, error) {
 }
 
 func (d *Drawer) Canvas(img image.Image) error {
-	return d.mixer.Canvas(img)
+	return d.CanvasContext(context.Background(), img)
+}
+
+// CanvasContext draws until ctx is done or Abort is called
+func (d *Drawer) CanvasContext(ctx context.Context, img image.Image) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	d.cl.Lock()
+	d.cancel = cancel
+	d.cl.Unlock()
+
+	ctx = proto.WithProgress(ctx, func(sent, total int) {
+		d.logger.With(zap.Int("sent", sent), zap.Int("total", total)).Debug("uploading")
+	})
+
+	return d.mixer.CanvasContext(ctx, img)
+}
+
+// Abort cancels the canvas being uploaded, if any
+func (d *Drawer) Abort() {
+	d.cl.Lock()
+	defer d.cl.Unlock()
+
+	if d.cancel != nil {
+		d.cancel()
+		d.cancel = nil
+	}
 }
@@ -5,6 +5,8 @@ import (
 	"time"
 
 	"github.com/moolex/wallhaven-go/api"
+
+	"usbscreen/pkg/proto"
 )
 
 func NewParams(width, height int) *Params {
@@ -71,7 +73,12 @@ func (p *Params) SwapRatio() {
 }
 
 func (p *Params) GetLight() uint8 {
-	return uint8((1 - float64(p.ScreenLight)/100) * 255)
+	return proto.LightLevel(p.ScreenLight)
+}
+
+// LightPercent converts the device light level back to screen light
+func (p *Params) LightPercent(light uint8) uint8 {
+	return proto.LightPercent(light)
 }
 
 func (p *Params) GetQuery() *api.QueryCond {
@@ -0,0 +1,173 @@
+package bitmap
+
+import (
+	"image"
+	"image/draw"
+
+	"github.com/pkg/errors"
+)
+
+// DitherMode decides how 8 bits channels are reduced to the panel format, RGB565 by default
+type DitherMode int
+
+const (
+	// DitherNone truncates every channel, same as Encode
+	DitherNone DitherMode = iota
+	// DitherFloydSteinberg diffuses the error to the next pixels, best for photos.
+	// A change anywhere alters the pixels after it, see the mixer for partial updates.
+	DitherFloydSteinberg
+	// DitherBayer adds a 4x4 ordered threshold, stable between similar frames
+	DitherBayer
+)
+
+var ditherNames = map[string]DitherMode{
+	"none":            DitherNone,
+	"floyd-steinberg": DitherFloydSteinberg,
+	"fs":              DitherFloydSteinberg,
+	"bayer":           DitherBayer,
+}
+
+func ParseDither(name string) (DitherMode, error) {
+	if name == "" {
+		return DitherNone, nil
+	}
+	if mode, ok := ditherNames[name]; ok {
+		return mode, nil
+	}
+	return DitherNone, errors.Errorf("unknown dither mode %q", name)
+}
+
+var bayer4 = [4][4]int32{
+	{0, 8, 2, 10},
+	{12, 4, 14, 6},
+	{3, 11, 1, 9},
+	{15, 7, 13, 5},
+}
+
+// EncodeDither is Encode with the colors dithered by mode
+func EncodeDither(src image.Image, mode DitherMode) []byte {
+	if mode == DitherNone {
+		return Encode(src)
+	}
+
+	b := src.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(dst, dst.Bounds(), src, b.Min, draw.Src)
+
+	Dither(dst, mode)
+	return Encode(dst)
+}
+
+// Dither rounds img in place to colors exactly representable in RGB565,
+// so Encode keeps them as they are. Transparent pixels are skipped.
+func Dither(img *image.RGBA, mode DitherMode) {
+	DitherFormat(img, mode, FormatRGB565)
+}
+
+// DitherFormat is Dither to the channel depths of f, 24 bits formats are left as they are
+func DitherFormat(img *image.RGBA, mode DitherMode, f Format) {
+	levels, ok := f.levels()
+	if !ok {
+		return
+	}
+
+	switch mode {
+	case DitherFloydSteinberg:
+		floydSteinberg(img, levels)
+	case DitherBayer:
+		orderedBayer(img, levels)
+	}
+}
+
+// levels of the red, green and blue channels, false if they keep 8 bits
+func (f Format) levels() ([3]int32, bool) {
+	switch f.bits() {
+	case 16:
+		// the same for BGR, red and blue both have 5 bits
+		return [3]int32{31, 63, 31}, true
+	case 18:
+		return [3]int32{63, 63, 63}, true
+	}
+	return [3]int32{}, false
+}
+
+func floydSteinberg(img *image.RGBA, levels [3]int32) {
+	b := img.Bounds()
+	w := b.Dx()
+
+	// errors carried to the current and the next row, one pixel padding on both sides
+	curr := make([]int32, (w+2)*3)
+	next := make([]int32, (w+2)*3)
+
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		pix := img.Pix[img.PixOffset(b.Min.X, y):]
+		for x := 0; x < w; x++ {
+			p := pix[x*4 : x*4+4 : x*4+4]
+			if p[3] == 0 {
+				continue
+			}
+
+			for c := 0; c < 3; c++ {
+				e := (x + 1) * 3
+				v := clamp8(int32(p[c]) + curr[e+c]/16)
+				// rounded to the nearest level
+				q := quantize(v, levels[c], 1, 127)
+				p[c] = byte(q)
+
+				diff := v - q
+				curr[e+3+c] += diff * 7
+				next[e-3+c] += diff * 3
+				next[e+c] += diff * 5
+				next[e+3+c] += diff
+			}
+		}
+
+		curr, next = next, curr
+		for i := range next {
+			next[i] = 0
+		}
+	}
+}
+
+func orderedBayer(img *image.RGBA, levels [3]int32) {
+	b := img.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		pix := img.Pix[img.PixOffset(b.Min.X, y):]
+		for x := 0; x < b.Dx(); x++ {
+			p := pix[x*4 : x*4+4 : x*4+4]
+			if p[3] == 0 {
+				continue
+			}
+
+			// threshold (m+0.5)/16 of a level, in 1/32 steps, anchored at 0,0 so
+			// sub images get the same pattern as the whole frame
+			t := (2*bayer4[y&3][(b.Min.X+x)&3] + 1) * 255
+			for c := 0; c < 3; c++ {
+				p[c] = byte(quantize(int32(p[c]), levels[c], 32, t))
+			}
+		}
+	}
+}
+
+// quantize maps v in [0, 255] to one of n+1 levels as floor(v*n/255 + bias/(255*scale)),
+// then back to 8 bits replicating the high bits as the RGB565 color does, n is 31 or 63.
+func quantize(v int32, n int32, scale int32, bias int32) int32 {
+	q := (v*n*scale + bias) / (255 * scale)
+	if q > n {
+		q = n
+	}
+
+	if n == 63 {
+		return q<<2 | q>>4
+	}
+	return q<<3 | q>>2
+}
+
+func clamp8(v int32) int32 {
+	if v < 0 {
+		return 0
+	} else if v > 255 {
+		return 255
+	}
+	return v
+}
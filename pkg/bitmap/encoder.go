@@ -2,17 +2,126 @@ package bitmap
 
 import (
 	"image"
+	"image/color"
 )
 
 func Encode(src image.Image) []byte {
 	b := src.Bounds()
-	d := NewRGB565(b)
+	if d, ok := src.(*RGB565); ok && b.Min == (image.Point{}) {
+		return d.pixels
+	}
+
+	// sub images keep their origin, the buffer always starts at 0,0
+	d := NewRGB565(image.Rect(0, 0, b.Dx(), b.Dy()))
 
-	for x := b.Min.X; x < b.Max.X; x++ {
+	// transparent pixels are left black as Set does
+	switch s := src.(type) {
+	case *image.RGBA:
+		encodeRGBA(d, s)
+	case *image.NRGBA:
+		encodeNRGBA(d, s)
+	case *image.YCbCr:
+		encodeYCbCr(d, s)
+	case *image.Paletted:
+		encodePaletted(d, s)
+	default:
 		for y := b.Min.Y; y < b.Max.Y; y++ {
-			d.Set(x, y, src.At(x, y))
+			for x := b.Min.X; x < b.Max.X; x++ {
+				d.Set(x-b.Min.X, y-b.Min.Y, src.At(x, y))
+			}
 		}
 	}
 
 	return d.pixels
 }
+
+func encodeRGBA(d *RGB565, s *image.RGBA) {
+	b := s.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		pix := s.Pix[s.PixOffset(b.Min.X, y):]
+		dst := d.pixels[(y-b.Min.Y)*d.pitch:]
+		for x := 0; x < b.Dx(); x++ {
+			p := pix[x*4 : x*4+4 : x*4+4]
+			if p[3] == 0 {
+				continue
+			}
+			// the 8 bits channel doubled to 16 bits keeps its high bits
+			put(dst, x, uint16(p[0]&0xF8)<<8|uint16(p[1]&0xFC)<<3|uint16(p[2])>>3)
+		}
+	}
+}
+
+func encodeNRGBA(d *RGB565, s *image.NRGBA) {
+	b := s.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		pix := s.Pix[s.PixOffset(b.Min.X, y):]
+		dst := d.pixels[(y-b.Min.Y)*d.pitch:]
+		for x := 0; x < b.Dx(); x++ {
+			p := pix[x*4 : x*4+4 : x*4+4]
+			if p[3] == 0 {
+				continue
+			}
+			if p[3] == 0xFF {
+				put(dst, x, uint16(p[0]&0xF8)<<8|uint16(p[1]&0xFC)<<3|uint16(p[2])>>3)
+				continue
+			}
+			// premultiplied the same way as color.NRGBA
+			a := uint32(p[3]) * 0x101
+			r := uint32(p[0]) * 0x101 * a / 0xFFFF
+			g := uint32(p[1]) * 0x101 * a / 0xFFFF
+			bl := uint32(p[2]) * 0x101 * a / 0xFFFF
+			put(dst, x, uint16(toRGB565(r, g, bl)))
+		}
+	}
+}
+
+func encodeYCbCr(d *RGB565, s *image.YCbCr) {
+	b := s.Bounds()
+
+	// the chroma offset is a row part plus a column part for every subsample ratio
+	cols := make([]int, b.Dx())
+	for x := range cols {
+		cols[x] = s.COffset(b.Min.X+x, b.Min.Y) - s.COffset(b.Min.X, b.Min.Y)
+	}
+
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		yy := s.Y[s.YOffset(b.Min.X, y):]
+		cb := s.Cb[s.COffset(b.Min.X, y):]
+		cr := s.Cr[s.COffset(b.Min.X, y):]
+		dst := d.pixels[(y-b.Min.Y)*d.pitch:]
+		for x, ci := range cols {
+			r, g, bl, _ := color.YCbCr{Y: yy[x], Cb: cb[ci], Cr: cr[ci]}.RGBA()
+			put(dst, x, uint16(toRGB565(r, g, bl)))
+		}
+	}
+}
+
+func encodePaletted(d *RGB565, s *image.Paletted) {
+	// colors out of the palette are left black
+	var table [256]uint16
+	var opaque [256]bool
+	for i, c := range s.Palette {
+		if i >= len(table) {
+			break
+		}
+		r, g, bl, a := c.RGBA()
+		table[i], opaque[i] = uint16(toRGB565(r, g, bl)), a > 0
+	}
+
+	b := s.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		pix := s.Pix[s.PixOffset(b.Min.X, y):]
+		dst := d.pixels[(y-b.Min.Y)*d.pitch:]
+		for x := 0; x < b.Dx(); x++ {
+			if i := pix[x]; opaque[i] {
+				put(dst, x, table[i])
+			}
+		}
+	}
+}
+
+// put stores the pixel at column x of a little endian row
+func put(row []byte, x int, c uint16) {
+	row[x*2] = byte(c & 0xFF)
+	row[x*2+1] = byte(c >> 8)
+}
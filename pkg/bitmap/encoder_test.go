@@ -0,0 +1,186 @@
+package bitmap
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/color/palette"
+	"math/rand"
+	"testing"
+)
+
+// generic hides the concrete type so Encode takes the At path
+type generic struct {
+	image.Image
+}
+
+// encodeBaseline is the column-major At and Set loop Encode had before the fast paths,
+// with the buffer moved to 0,0 as Encode does for sub images
+func encodeBaseline(src image.Image) []byte {
+	b := src.Bounds()
+	d := NewRGB565(image.Rect(0, 0, b.Dx(), b.Dy()))
+
+	for x := b.Min.X; x < b.Max.X; x++ {
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			d.Set(x-b.Min.X, y-b.Min.Y, src.At(x, y))
+		}
+	}
+
+	return d.pixels
+}
+
+func fill(seed int64, set func(x, y int, c color.Color), r image.Rectangle) {
+	rnd := rand.New(rand.NewSource(seed))
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			// every fourth pixel is fully transparent or opaque to hit both shortcuts
+			a := uint8(rnd.Intn(256))
+			switch rnd.Intn(4) {
+			case 0:
+				a = 0
+			case 1:
+				a = 0xFF
+			}
+			set(x, y, color.NRGBA{R: uint8(rnd.Intn(256)), G: uint8(rnd.Intn(256)), B: uint8(rnd.Intn(256)), A: a})
+		}
+	}
+}
+
+func newRGBA(r image.Rectangle) *image.RGBA {
+	img := image.NewRGBA(r)
+	fill(1, img.Set, r)
+	return img
+}
+
+func newNRGBA(r image.Rectangle) *image.NRGBA {
+	img := image.NewNRGBA(r)
+	fill(2, img.Set, r)
+	return img
+}
+
+func newYCbCr(r image.Rectangle, ratio image.YCbCrSubsampleRatio) *image.YCbCr {
+	img := image.NewYCbCr(r, ratio)
+	rnd := rand.New(rand.NewSource(3))
+	rnd.Read(img.Y)
+	rnd.Read(img.Cb)
+	rnd.Read(img.Cr)
+	return img
+}
+
+func newPaletted(r image.Rectangle) *image.Paletted {
+	pal := append(color.Palette{color.Transparent}, palette.Plan9[:200]...)
+	img := image.NewPaletted(r, pal)
+	rnd := rand.New(rand.NewSource(4))
+	for i := range img.Pix {
+		img.Pix[i] = uint8(rnd.Intn(len(pal)))
+	}
+	return img
+}
+
+func newRGB565(r image.Rectangle) *RGB565 {
+	img := NewRGB565(r)
+	rnd := rand.New(rand.NewSource(5))
+	rnd.Read(img.pixels)
+	return img
+}
+
+type subImager interface {
+	image.Image
+	SubImage(r image.Rectangle) image.Image
+}
+
+func testImages() map[string]image.Image {
+	r := image.Rect(0, 0, 67, 41)
+	images := map[string]image.Image{
+		"rgba":       newRGBA(r),
+		"nrgba":      newNRGBA(r),
+		"paletted":   newPaletted(r),
+		"rgb565":     newRGB565(r),
+		"ycbcr444":   newYCbCr(r, image.YCbCrSubsampleRatio444),
+		"ycbcr422":   newYCbCr(r, image.YCbCrSubsampleRatio422),
+		"ycbcr420":   newYCbCr(r, image.YCbCrSubsampleRatio420),
+		"ycbcr440":   newYCbCr(r, image.YCbCrSubsampleRatio440),
+		"ycbcr411":   newYCbCr(r, image.YCbCrSubsampleRatio411),
+		"ycbcr410":   newYCbCr(r, image.YCbCrSubsampleRatio410),
+		"rgba-moved": newRGBA(r.Add(image.Pt(-13, 7))),
+	}
+
+	// sub images at odd offsets, so chroma and rows do not start at a subsample boundary
+	subs := make(map[string]image.Image)
+	for name, img := range images {
+		if s, ok := img.(subImager); ok {
+			subs[name+"-sub"] = s.SubImage(image.Rect(5, 3, 50, 38).Add(img.Bounds().Min))
+		}
+	}
+	for name, img := range subs {
+		images[name] = img
+	}
+	return images
+}
+
+func TestEncodeFastPaths(t *testing.T) {
+	for name, img := range testImages() {
+		want := encodeBaseline(img)
+		compareEncoded(t, name, img.Bounds(), Encode(img), want)
+		compareEncoded(t, name+"-generic", img.Bounds(), Encode(generic{img}), want)
+	}
+}
+
+func compareEncoded(t *testing.T, name string, b image.Rectangle, got, want []byte) {
+	t.Helper()
+	if bytes.Equal(got, want) {
+		return
+	}
+
+	for i := 0; i < len(got); i += 2 {
+		if got[i] != want[i] || got[i+1] != want[i+1] {
+			x, y := b.Min.X+i/2%b.Dx(), b.Min.Y+i/2/b.Dx()
+			t.Errorf("%s: pixel %d,%d is %02x%02x, want %02x%02x", name, x, y, got[i+1], got[i], want[i+1], want[i])
+			return
+		}
+	}
+}
+
+func benchmarkEncode(b *testing.B, img image.Image) {
+	benchmarkEncoder(b, Encode, img)
+}
+
+func benchmarkEncoder(b *testing.B, encode func(image.Image) []byte, img image.Image) {
+	b.SetBytes(int64(img.Bounds().Dx() * img.Bounds().Dy() * 2))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		encode(img)
+	}
+}
+
+var benchRect = image.Rect(0, 0, 320, 480)
+
+func BenchmarkEncodeRGBA(b *testing.B) {
+	benchmarkEncode(b, newRGBA(benchRect))
+}
+
+func BenchmarkEncodeNRGBA(b *testing.B) {
+	benchmarkEncode(b, newNRGBA(benchRect))
+}
+
+func BenchmarkEncodeYCbCr(b *testing.B) {
+	benchmarkEncode(b, newYCbCr(benchRect, image.YCbCrSubsampleRatio420))
+}
+
+func BenchmarkEncodePaletted(b *testing.B) {
+	benchmarkEncode(b, newPaletted(benchRect))
+}
+
+func BenchmarkEncodeRGB565(b *testing.B) {
+	benchmarkEncode(b, newRGB565(benchRect))
+}
+
+func BenchmarkEncodeGeneric(b *testing.B) {
+	benchmarkEncode(b, generic{newRGBA(benchRect)})
+}
+
+// BenchmarkEncodeBaseline is the loop before the fast paths, to compare the others with
+func BenchmarkEncodeBaseline(b *testing.B) {
+	benchmarkEncoder(b, encodeBaseline, newRGBA(benchRect))
+}
@@ -0,0 +1,160 @@
+package bitmap
+
+import (
+	"image"
+	"image/color"
+
+	"github.com/pkg/errors"
+)
+
+// Format is the pixel layout a panel takes, the zero value is RGB565 in little endian.
+// 18 and 24 bits formats are 3 bytes per pixel in channel order, each channel aligned
+// to the high bits, so BigEndian only applies to the 16 bits ones.
+type Format struct {
+	Bits      int
+	BGR       bool
+	BigEndian bool
+}
+
+var (
+	FormatRGB565   = Format{Bits: 16}
+	FormatRGB565BE = Format{Bits: 16, BigEndian: true}
+	FormatBGR565   = Format{Bits: 16, BGR: true}
+	FormatBGR565BE = Format{Bits: 16, BGR: true, BigEndian: true}
+	FormatRGB666   = Format{Bits: 18}
+	FormatBGR666   = Format{Bits: 18, BGR: true}
+	FormatRGB888   = Format{Bits: 24}
+	FormatBGR888   = Format{Bits: 24, BGR: true}
+)
+
+var formats = []Format{FormatRGB565, FormatRGB565BE, FormatBGR565, FormatBGR565BE, FormatRGB666, FormatBGR666, FormatRGB888, FormatBGR888}
+
+// ParseFormat takes the names printed by Format.String, e.g. rgb565, bgr565be or rgb888
+func ParseFormat(name string) (Format, error) {
+	if name == "" {
+		return FormatRGB565, nil
+	}
+	for _, f := range formats {
+		if f.String() == name {
+			return f, nil
+		}
+	}
+	return FormatRGB565, errors.Errorf("unknown pixel format %q", name)
+}
+
+func (f Format) String() string {
+	name := "rgb"
+	if f.BGR {
+		name = "bgr"
+	}
+
+	switch f.bits() {
+	case 18:
+		return name + "666"
+	case 24:
+		return name + "888"
+	}
+
+	name += "565"
+	if f.BigEndian {
+		name += "be"
+	}
+	return name
+}
+
+// Size is the bytes per pixel
+func (f Format) Size() int {
+	if f.bits() == 16 {
+		return 2
+	}
+	return 3
+}
+
+func (f Format) bits() int {
+	if f.Bits == 0 {
+		return 16
+	}
+	return f.Bits
+}
+
+// Pixel returns c in the format, transparency is ignored
+func (f Format) Pixel(c color.Color) []byte {
+	r, g, b, _ := c.RGBA()
+	p := make([]byte, f.Size())
+	f.put(p, r, g, b)
+	return p
+}
+
+// put writes the 16 bits channels to dst, the truncation is the same as toRGB565
+func (f Format) put(dst []byte, r, g, b uint32) {
+	if f.BGR {
+		r, b = b, r
+	}
+
+	switch f.bits() {
+	case 16:
+		f.put16(dst, uint16(toRGB565(r, g, b)))
+	case 18:
+		dst[0], dst[1], dst[2] = byte(r>>8)&0xFC, byte(g>>8)&0xFC, byte(b>>8)&0xFC
+	default:
+		dst[0], dst[1], dst[2] = byte(r>>8), byte(g>>8), byte(b>>8)
+	}
+}
+
+func (f Format) put16(dst []byte, v uint16) {
+	if f.BigEndian {
+		dst[0], dst[1] = byte(v>>8), byte(v&0xFF)
+	} else {
+		dst[0], dst[1] = byte(v&0xFF), byte(v>>8)
+	}
+}
+
+// EncodeFormat is Encode for any format, 16 bits formats are converted from the RGB565 output
+func EncodeFormat(src image.Image, f Format) []byte {
+	if f.bits() == 16 {
+		pix := Encode(src)
+		if f == FormatRGB565 || f == (Format{}) {
+			return pix
+		}
+
+		out := make([]byte, len(pix))
+		for i := 0; i < len(pix); i += 2 {
+			v := uint16(pix[i]) | uint16(pix[i+1])<<8
+			if f.BGR {
+				v = v<<11 | v&0x07E0 | v>>11
+			}
+			f.put16(out[i:], v)
+		}
+		return out
+	}
+
+	b := src.Bounds()
+	size := f.Size()
+	out := make([]byte, b.Dx()*b.Dy()*size)
+
+	// transparent pixels are left black as Encode does
+	if s, ok := src.(*image.RGBA); ok {
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			pix := s.Pix[s.PixOffset(b.Min.X, y):]
+			dst := out[(y-b.Min.Y)*b.Dx()*size:]
+			for x := 0; x < b.Dx(); x++ {
+				p := pix[x*4 : x*4+4 : x*4+4]
+				if p[3] > 0 {
+					f.put(dst[x*size:], uint32(p[0])*0x101, uint32(p[1])*0x101, uint32(p[2])*0x101)
+				}
+			}
+		}
+		return out
+	}
+
+	i := 0
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			if r, g, bl, a := src.At(x, y).RGBA(); a > 0 {
+				f.put(out[i:], r, g, bl)
+			}
+			i += size
+		}
+	}
+	return out
+}
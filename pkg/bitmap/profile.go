@@ -0,0 +1,167 @@
+package bitmap
+
+import (
+	"encoding/json"
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"os"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// Profile calibrates the colors of a panel, applied before the RGB565 quantization.
+// Saturation goes first, then the gamma, the white point and the curves of every channel.
+//
+//	{
+//	  "gamma": 1.2,
+//	  "white_point": [1, 0.95, 0.9],
+//	  "saturation": 1.1,
+//	  "curves": {"r": [0, 120, 255], "b": [0, 255]}
+//	}
+type Profile struct {
+	// Gamma is the exponent of the normalized channels, above 1 darkens the midtones, 0 keeps
+	Gamma float64 `json:"gamma"`
+	// WhitePoint scales red, green and blue in 0-1, empty keeps
+	WhitePoint []float64 `json:"white_point"`
+	// Saturation moves the colors away from their luma, 0 keeps
+	Saturation float64 `json:"saturation"`
+	// Curves are evenly spaced points over 0-255 per channel r, g and b, interpolated linearly
+	Curves map[string][]float64 `json:"curves"`
+
+	once   sync.Once
+	tables [3][256]uint8
+}
+
+func LoadProfile(file string) (*Profile, error) {
+	bs, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &Profile{}
+	if err := json.Unmarshal(bs, p); err != nil {
+		return nil, errors.Wrapf(err, "invalid profile %s", file)
+	}
+
+	if err := p.validate(); err != nil {
+		return nil, errors.Wrapf(err, "invalid profile %s", file)
+	}
+	return p, nil
+}
+
+func (p *Profile) validate() error {
+	if p.Gamma < 0 || p.Saturation < 0 {
+		return errors.New("gamma and saturation could not be negative")
+	}
+	if len(p.WhitePoint) != 0 && len(p.WhitePoint) != 3 {
+		return errors.New("white point needs red, green and blue")
+	}
+	for name, points := range p.Curves {
+		if name != "r" && name != "g" && name != "b" {
+			return errors.Errorf("unknown curve %q", name)
+		}
+		if len(points) < 2 {
+			return errors.Errorf("curve %s needs 2 points at least", name)
+		}
+	}
+	return nil
+}
+
+// Calibrate returns a copy of src with the profile applied, starting at 0,0
+func (p *Profile) Calibrate(src image.Image) *image.RGBA {
+	b := src.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(dst, dst.Bounds(), src, b.Min, draw.Src)
+
+	p.Apply(dst)
+	return dst
+}
+
+// Color returns c with the profile applied
+func (p *Profile) Color(c color.Color) color.Color {
+	img := image.NewRGBA(image.Rect(0, 0, 1, 1))
+	img.Set(0, 0, c)
+	p.Apply(img)
+	return img.RGBAAt(0, 0)
+}
+
+// Apply calibrates img in place, partially transparent pixels are corrected unpremultiplied
+func (p *Profile) Apply(img *image.RGBA) {
+	p.once.Do(p.build)
+
+	b := img.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		pix := img.Pix[img.PixOffset(b.Min.X, y):]
+		for x := 0; x < b.Dx(); x++ {
+			px := pix[x*4 : x*4+4 : x*4+4]
+			a := int32(px[3])
+			if a == 0 {
+				continue
+			}
+
+			var c [3]int32
+			for i := range c {
+				c[i] = int32(px[i])
+				if a < 0xFF {
+					c[i] = clamp8(c[i] * 0xFF / a)
+				}
+			}
+
+			if p.Saturation > 0 && p.Saturation != 1 {
+				c = saturate(c, p.Saturation)
+			}
+
+			for i := range c {
+				v := int32(p.tables[i][c[i]])
+				if a < 0xFF {
+					v = v * a / 0xFF
+				}
+				px[i] = uint8(v)
+			}
+		}
+	}
+}
+
+func (p *Profile) build() {
+	for i, name := range []string{"r", "g", "b"} {
+		white := 1.0
+		if len(p.WhitePoint) == 3 {
+			white = p.WhitePoint[i]
+		}
+
+		for v := 0; v < 256; v++ {
+			f := float64(v) / 255
+			if p.Gamma > 0 {
+				f = math.Pow(f, p.Gamma)
+			}
+			f *= white
+			if points := p.Curves[name]; len(points) >= 2 {
+				f = curve(points, f*255) / 255
+			}
+			p.tables[i][v] = uint8(math.Round(math.Max(0, math.Min(1, f)) * 255))
+		}
+	}
+}
+
+// curve interpolates evenly spaced points over 0-255 at v
+func curve(points []float64, v float64) float64 {
+	pos := v / 255 * float64(len(points)-1)
+	i := int(pos)
+	if i >= len(points)-1 {
+		return points[len(points)-1]
+	}
+	return points[i] + (points[i+1]-points[i])*(pos-float64(i))
+}
+
+// saturate scales the distance of every channel to the Rec. 601 luma
+func saturate(c [3]int32, s float64) [3]int32 {
+	luma := 0.299*float64(c[0]) + 0.587*float64(c[1]) + 0.114*float64(c[2])
+	for i := range c {
+		v := luma + (float64(c[i])-luma)*s
+		c[i] = int32(math.Round(math.Max(0, math.Min(255, v))))
+	}
+	return c
+}
@@ -16,6 +16,16 @@ func NewRGB565(r image.Rectangle) *RGB565 {
 	}
 }
 
+// WrapRGB565 uses already encoded little endian pixels as image, the min bounds must be at 0,0.
+func WrapRGB565(r image.Rectangle, pixels []byte) *RGB565 {
+	return &RGB565{
+		pixels:     pixels,
+		pitch:      2 * r.Dx(),
+		bounds:     r,
+		colorModel: rgb565ColorModel{},
+	}
+}
+
 // RGB565 represents the frame buffer. It implements the draw.Image interface.
 type RGB565 struct {
 	pixels     []byte
@@ -53,15 +63,24 @@ func (d *RGB565) Set(x, y int, c color.Color) {
 		if a > 0 {
 			rgb := toRGB565(r, g, b)
 			i := y*d.pitch + 2*x
-			// This assumes a little endian system which is the default for
-			// Raspbian. The d.pixels indices have to be swapped if the target
-			// system is big endian.
+			// Stored in little endian as the panels take it, see Format
+			// and EncodeFormat for the other layouts.
 			d.pixels[i+1] = byte(rgb >> 8)
 			d.pixels[i] = byte(rgb & 0xFF)
 		}
 	}
 }
 
+// SetRGB565 stores an already converted pixel value, skipping the color model.
+func (d *RGB565) SetRGB565(x, y int, c uint16) {
+	if x >= 0 && x < d.bounds.Max.X &&
+		y >= 0 && y < d.bounds.Max.Y {
+		i := y*d.pitch + 2*x
+		d.pixels[i+1] = byte(c >> 8)
+		d.pixels[i] = byte(c & 0xFF)
+	}
+}
+
 // The default color model under the Raspberry Pi is RGB 565. Each pixel is
 // represented by two bytes, with 5 bits for red, 6 bits for green and 5 bits
 // for blue. There is no alpha channel, so alpha is assumed to always be 100%
@@ -78,6 +97,12 @@ func (rgb565ColorModel) Convert(c color.Color) color.Color {
 	return toRGB565(r, g, b)
 }
 
+// ToRGB565 returns the 16 bits pixel value of c, same as the one written by Set.
+func ToRGB565(c color.Color) uint16 {
+	r, g, b, _ := c.RGBA()
+	return uint16(toRGB565(r, g, b))
+}
+
 // toRGB565 helps convert a color.Color to rgb565. In a color.Color each
 // channel is represented by the lower 16 bits in a uint32 so the maximum value
 // is 0xFFFF. This function simply uses the highest 5 or 6 bits of each channel
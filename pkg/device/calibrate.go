@@ -0,0 +1,70 @@
+package device
+
+import (
+	"context"
+	"image"
+	"image/color"
+
+	"github.com/pkg/errors"
+
+	"usbscreen/pkg/bitmap"
+	"usbscreen/pkg/proto"
+)
+
+// Calibrate corrects the colors drawn on dev with the profile, other calls go to dev as they are.
+// A device calibrated already is refused, the profiles would be applied twice.
+func Calibrate(dev proto.Control, profile *bitmap.Profile) (proto.Control, error) {
+	if _, ok := dev.(proto.Calibrator); ok {
+		return nil, errors.New("device is calibrated already, e.g. by profile= of its spec")
+	}
+
+	return &calibrated{
+		Control:        dev,
+		ContextControl: proto.WithContext(dev),
+		profile:        profile,
+	}, nil
+}
+
+type calibrated struct {
+	proto.Control
+	proto.ContextControl
+	profile *bitmap.Profile
+}
+
+func (c *calibrated) DrawBitmap(posX uint16, posY uint16, image image.Image) error {
+	return c.DrawBitmapContext(context.Background(), posX, posY, image)
+}
+
+func (c *calibrated) DrawBitmapContext(ctx context.Context, posX uint16, posY uint16, image image.Image) error {
+	return c.ContextControl.DrawBitmapContext(ctx, posX, posY, c.profile.Calibrate(image))
+}
+
+func (c *calibrated) DrawPixels(offsetX uint16, offsetY uint16, color color.Color, coordinates []uint8) error {
+	return c.DrawPixelsContext(context.Background(), offsetX, offsetY, color, coordinates)
+}
+
+func (c *calibrated) DrawPixelsContext(ctx context.Context, offsetX uint16, offsetY uint16, color color.Color, coordinates []uint8) error {
+	return c.ContextControl.DrawPixelsContext(ctx, offsetX, offsetY, c.profile.Color(color), coordinates)
+}
+
+// Calibration hands out the adapted device, calls through it wait for the ones made here
+func (c *calibrated) Calibration() (*bitmap.Profile, proto.ContextControl) {
+	return c.profile, c.ContextControl
+}
+
+func (c *calibrated) Close() error {
+	return Close(c.Control)
+}
+
+func (c *calibrated) OnReset(fn func()) {
+	if n, ok := c.Control.(proto.ResetNotifier); ok {
+		n.OnReset(fn)
+	}
+}
+
+func (c *calibrated) Format() bitmap.Format {
+	if r, ok := c.Control.(proto.FormatReporter); ok {
+		return r.Format()
+	}
+	return bitmap.FormatRGB565
+}
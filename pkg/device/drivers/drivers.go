@@ -0,0 +1,11 @@
+// Package drivers registers all builtin devices, import it for side effects.
+package drivers
+
+import (
+	_ "usbscreen/pkg/device/inch21"
+	_ "usbscreen/pkg/device/inch35"
+	_ "usbscreen/pkg/device/inch50"
+	_ "usbscreen/pkg/device/multi"
+	_ "usbscreen/pkg/device/remote"
+	_ "usbscreen/pkg/device/virtual"
+)
@@ -0,0 +1,26 @@
+package inch21
+
+import (
+	"go.uber.org/zap"
+
+	"usbscreen/pkg/device/panel"
+	"usbscreen/pkg/proto"
+)
+
+// Model is the 2.1 inch round 480x480 panel, its brightness goes from 0 (brightest) to 100.
+var Model = &panel.Model{
+	Name:         "inch21",
+	Width:        480,
+	Height:       480,
+	Orientations: [4]uint8{100, 101, 102, 103},
+	MaxLight:     100,
+	BaudRate:     115200,
+}
+
+func init() {
+	panel.Register(Model)
+}
+
+func New(serial *proto.Serial, logger *zap.Logger, opts ...panel.Option) (proto.Control, error) {
+	return panel.New(serial, logger, Model, opts...)
+}
@@ -0,0 +1,26 @@
+package inch50
+
+import (
+	"go.uber.org/zap"
+
+	"usbscreen/pkg/device/panel"
+	"usbscreen/pkg/proto"
+)
+
+// Model is the 5 inch 480x800 panel.
+var Model = &panel.Model{
+	Name:         "inch50",
+	Width:        480,
+	Height:       800,
+	Orientations: [4]uint8{100, 101, 102, 103},
+	MaxLight:     255,
+	BaudRate:     115200,
+}
+
+func init() {
+	panel.Register(Model)
+}
+
+func New(serial *proto.Serial, logger *zap.Logger, opts ...panel.Option) (proto.Control, error) {
+	return panel.New(serial, logger, Model, opts...)
+}
@@ -0,0 +1,57 @@
+package multi
+
+import (
+	"net/url"
+
+	"usbscreen/pkg/device"
+	"usbscreen/pkg/proto"
+)
+
+func init() {
+	device.Register(&device.Driver{
+		Name: "multi",
+		New:  open,
+	})
+}
+
+// Spec builds the device spec stitching devs into grid
+func Spec(grid string, devs []string) string {
+	return "multi://" + grid + "?" + url.Values{"dev": devs}.Encode()
+}
+
+// open accepts multi://2x1?dev=inch35:///dev/ttyACM0&dev=inch35:///dev/ttyACM1
+func open(spec *device.Spec) (proto.Control, error) {
+	layout, err := ParseLayout(spec.Target())
+	if err != nil {
+		return nil, err
+	}
+
+	var devs []proto.Control
+	for _, sub := range spec.Values("dev") {
+		dev, err := spec.Open(sub)
+		if err != nil {
+			_ = device.Close(devs...)
+			return nil, err
+		}
+		devs = append(devs, dev)
+	}
+
+	if len(devs) > 0 {
+		// cells take the native size of the panels, or what the first one reports, e.g. behind a remote
+		if native, ok := device.Native(spec.Values("dev")[0]); ok {
+			layout.Width, layout.Height = native.X, native.Y
+		} else if state, err := devs[0].State(); err == nil {
+			layout.Width, layout.Height = state.Width, state.Height
+			if state.Landscape {
+				layout.Width, layout.Height = state.Height, state.Width
+			}
+		}
+	}
+
+	m, err := New(layout, devs...)
+	if err != nil {
+		_ = device.Close(devs...)
+		return nil, err
+	}
+	return m, nil
+}
@@ -0,0 +1,46 @@
+package multi
+
+import (
+	"fmt"
+	"image"
+
+	"github.com/pkg/errors"
+)
+
+// Layout arranges panels of the same size into a grid, Width and Height are of one panel in portrait
+type Layout struct {
+	Cols   int
+	Rows   int
+	Width  int
+	Height int
+}
+
+// ParseLayout parses grid like "2x1" (cols x rows) of 320x480 panels
+func ParseLayout(s string) (Layout, error) {
+	l := Layout{Width: 320, Height: 480}
+	if _, err := fmt.Sscanf(s, "%dx%d", &l.Cols, &l.Rows); err != nil {
+		return l, errors.Wrapf(err, "invalid grid %q", s)
+	}
+	if l.Cols <= 0 || l.Rows <= 0 {
+		return l, errors.Errorf("invalid grid %q", s)
+	}
+	return l, nil
+}
+
+func (l Layout) Count() int {
+	return l.Cols * l.Rows
+}
+
+// Cell returns the panel size after rotation
+func (l Layout) Cell(landscape bool) image.Point {
+	if landscape {
+		return image.Pt(l.Height, l.Width)
+	}
+	return image.Pt(l.Width, l.Height)
+}
+
+// Size returns the whole canvas size
+func (l Layout) Size(landscape bool) image.Point {
+	c := l.Cell(landscape)
+	return image.Pt(c.X*l.Cols, c.Y*l.Rows)
+}
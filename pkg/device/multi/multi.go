@@ -0,0 +1,312 @@
+package multi
+
+import (
+	"context"
+	"image"
+	"image/color"
+	"image/draw"
+	"io"
+	"sync"
+
+	"github.com/pkg/errors"
+	"go.uber.org/multierr"
+
+	"usbscreen/pkg/bitmap"
+	"usbscreen/pkg/proto"
+)
+
+// New stitches devices into one canvas, devices are listed row by row as placed on the wall
+func New(layout Layout, devs ...proto.Control) (proto.Control, error) {
+	if len(devs) != layout.Count() {
+		return nil, errors.Errorf("grid %dx%d needs %d devices, got %d", layout.Cols, layout.Rows, layout.Count(), len(devs))
+	}
+
+	m := &Multi{layout: layout}
+	for i, dev := range devs {
+		if i == 0 {
+			m.first = dev
+		}
+		m.devs = append(m.devs, proto.WithContext(dev))
+		if n, ok := dev.(proto.ResetNotifier); ok {
+			m.resets = append(m.resets, n)
+		}
+		if c, ok := dev.(io.Closer); ok {
+			m.closers = append(m.closers, c)
+		}
+	}
+	return m, nil
+}
+
+type Multi struct {
+	layout    Layout
+	first     proto.Control
+	devs      []proto.ContextControl
+	landscape bool
+	mirror    bool
+	resets    []proto.ResetNotifier
+	closers   []io.Closer
+}
+
+// Close closes every device holding a port or a connection
+func (m *Multi) Close() error {
+	var err error
+	for _, c := range m.closers {
+		err = multierr.Append(err, c.Close())
+	}
+	return err
+}
+
+// OnReset registers fn on every device able to lose its content
+func (m *Multi) OnReset(fn func()) {
+	for _, n := range m.resets {
+		n.OnReset(fn)
+	}
+}
+
+// Format is the one of the first device, a grid is made of the same panels
+func (m *Multi) Format() bitmap.Format {
+	if r, ok := m.first.(proto.FormatReporter); ok {
+		return r.Format()
+	}
+	return bitmap.FormatRGB565
+}
+
+func (m *Multi) State() (*proto.State, error) {
+	return m.StateContext(context.Background())
+}
+
+func (m *Multi) StateContext(ctx context.Context) (*proto.State, error) {
+	state, err := m.devs[0].StateContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	size := m.layout.Size(m.landscape)
+	state.Mirror = m.mirror
+	state.Width, state.Height = size.X, size.Y
+	return state, nil
+}
+
+type subImager interface {
+	image.Image
+	SubImage(image.Rectangle) image.Image
+}
+
+func (m *Multi) Startup() error {
+	return m.StartupContext(context.Background())
+}
+
+func (m *Multi) StartupContext(ctx context.Context) error {
+	return m.each(func(dev proto.ContextControl) error {
+		return dev.StartupContext(ctx)
+	})
+}
+
+func (m *Multi) Shutdown() error {
+	return m.ShutdownContext(context.Background())
+}
+
+func (m *Multi) ShutdownContext(ctx context.Context) error {
+	return m.each(func(dev proto.ContextControl) error {
+		return dev.ShutdownContext(ctx)
+	})
+}
+
+func (m *Multi) Restart() error {
+	return m.RestartContext(context.Background())
+}
+
+func (m *Multi) RestartContext(ctx context.Context) error {
+	return m.each(func(dev proto.ContextControl) error {
+		return dev.RestartContext(ctx)
+	})
+}
+
+func (m *Multi) SetLight(light uint8) error {
+	return m.SetLightContext(context.Background(), light)
+}
+
+func (m *Multi) SetLightContext(ctx context.Context, light uint8) error {
+	return m.each(func(dev proto.ContextControl) error {
+		return dev.SetLightContext(ctx, light)
+	})
+}
+
+func (m *Multi) SetMirror(mirror bool) error {
+	return m.SetMirrorContext(context.Background(), mirror)
+}
+
+func (m *Multi) SetMirrorContext(ctx context.Context, mirror bool) error {
+	err := m.each(func(dev proto.ContextControl) error {
+		return dev.SetMirrorContext(ctx, mirror)
+	})
+	if err == nil {
+		m.mirror = mirror
+	}
+	return err
+}
+
+func (m *Multi) SetRotate(landscape bool, invert bool) error {
+	return m.SetRotateContext(context.Background(), landscape, invert)
+}
+
+func (m *Multi) SetRotateContext(ctx context.Context, landscape bool, invert bool) error {
+	err := m.each(func(dev proto.ContextControl) error {
+		return dev.SetRotateContext(ctx, landscape, invert)
+	})
+	if err == nil {
+		m.landscape = landscape
+	}
+	return err
+}
+
+func (m *Multi) DrawBitmap(posX uint16, posY uint16, img image.Image) error {
+	return m.DrawBitmapContext(context.Background(), posX, posY, img)
+}
+
+func (m *Multi) DrawBitmapContext(ctx context.Context, posX uint16, posY uint16, img image.Image) error {
+	b := img.Bounds()
+	area := b.Sub(b.Min).Add(pt(posX, posY))
+
+	if !area.In(m.canvas()) {
+		return errors.New("bitmap overflow")
+	}
+
+	src, ok := img.(subImager)
+	if !ok {
+		src = toRGBA(img)
+	}
+
+	// sum up the progress of all panels uploading in parallel
+	var pl sync.Mutex
+	sent := make(map[image.Rectangle]int)
+	progress := proto.ProgressFrom(ctx)
+	total := area.Dx() * area.Dy() * m.Format().Size()
+
+	return m.cells(func(dev proto.ContextControl, cell image.Rectangle) error {
+		part := area.Intersect(cell)
+		if part.Empty() {
+			return nil
+		}
+
+		ctx2 := proto.WithProgress(ctx, func(n, _ int) {
+			pl.Lock()
+			defer pl.Unlock()
+
+			sent[cell] = n
+			var sum int
+			for _, v := range sent {
+				sum += v
+			}
+			progress(sum, total)
+		})
+
+		at := part.Min.Sub(cell.Min)
+		return dev.DrawBitmapContext(ctx2, uint16(at.X), uint16(at.Y), src.SubImage(part.Sub(area.Min).Add(b.Min)))
+	})
+}
+
+func (m *Multi) DrawPixels(offsetX uint16, offsetY uint16, color color.Color, coordinates []uint8) error {
+	return m.DrawPixelsContext(context.Background(), offsetX, offsetY, color, coordinates)
+}
+
+func (m *Multi) DrawPixelsContext(ctx context.Context, offsetX uint16, offsetY uint16, color color.Color, coordinates []uint8) error {
+	if len(coordinates)%2 != 0 {
+		return errors.New("coordinates not paired")
+	}
+
+	canvas := m.canvas()
+	offset := pt(offsetX, offsetY)
+	for n := 0; n < len(coordinates); n += 2 {
+		if !offset.Add(pt(uint16(coordinates[n]), uint16(coordinates[n+1]))).In(canvas) {
+			return errors.New("pixel overflow")
+		}
+	}
+
+	return m.cells(func(dev proto.ContextControl, cell image.Rectangle) error {
+		var points []image.Point
+		var local image.Point
+		for n := 0; n < len(coordinates); n += 2 {
+			p := offset.Add(pt(uint16(coordinates[n]), uint16(coordinates[n+1])))
+			if !p.In(cell) {
+				continue
+			}
+			p = p.Sub(cell.Min)
+			if len(points) == 0 || p.X < local.X {
+				local.X = p.X
+			}
+			if len(points) == 0 || p.Y < local.Y {
+				local.Y = p.Y
+			}
+			points = append(points, p)
+		}
+
+		if len(points) == 0 {
+			return nil
+		}
+
+		coords := make([]uint8, 0, len(points)*2)
+		for _, p := range points {
+			coords = append(coords, uint8(p.X-local.X), uint8(p.Y-local.Y))
+		}
+		return dev.DrawPixelsContext(ctx, uint16(local.X), uint16(local.Y), color, coords)
+	})
+}
+
+func (m *Multi) canvas() image.Rectangle {
+	s := m.layout.Size(m.landscape)
+	return image.Rect(0, 0, s.X, s.Y)
+}
+
+// cells calls fn with the device and its canvas region in parallel
+func (m *Multi) cells(fn func(dev proto.ContextControl, cell image.Rectangle) error) error {
+	size := m.layout.Cell(m.landscape)
+
+	var l sync.Mutex
+	var wg sync.WaitGroup
+	var errs error
+
+	for row := 0; row < m.layout.Rows; row++ {
+		for col := 0; col < m.layout.Cols; col++ {
+			// mirrored canvas is shown in reversed columns
+			devCol := col
+			if m.mirror {
+				devCol = m.layout.Cols - 1 - col
+			}
+
+			dev := m.devs[row*m.layout.Cols+devCol]
+			cell := image.Rect(col*size.X, row*size.Y, (col+1)*size.X, (row+1)*size.Y)
+
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if err := fn(dev, cell); err != nil {
+					l.Lock()
+					errs = multierr.Append(errs, err)
+					l.Unlock()
+				}
+			}()
+		}
+	}
+
+	wg.Wait()
+	return errs
+}
+
+func (m *Multi) each(fn func(dev proto.ContextControl) error) error {
+	var errs error
+	for _, dev := range m.devs {
+		errs = multierr.Append(errs, fn(dev))
+	}
+	return errs
+}
+
+func pt(x, y uint16) image.Point {
+	return image.Pt(int(x), int(y))
+}
+
+func toRGBA(img image.Image) *image.RGBA {
+	dst := image.NewRGBA(img.Bounds())
+	draw.Draw(dst, dst.Bounds(), img, img.Bounds().Min, draw.Src)
+	return dst
+}
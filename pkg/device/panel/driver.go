@@ -0,0 +1,81 @@
+package panel
+
+import (
+	"time"
+
+	"usbscreen/pkg/bitmap"
+	"usbscreen/pkg/device"
+	"usbscreen/pkg/proto"
+)
+
+// Register makes the model available as a device driver named after it
+func Register(model *Model) {
+	device.Register(&device.Driver{
+		Name:   model.Name,
+		New:    opener(model),
+		Width:  model.Width,
+		Height: model.Height,
+	})
+}
+
+// opener accepts <model>:///dev/ttyACM0 or <model>:usb:VID:PID[:SERIAL],
+// with query read-timeout, write-timeout, reconnect, handshake, ack-wait, chunk, rate and format,
+// handshake=0 saves the wait for the identification on panels which never answer
+func opener(model *Model) device.Constructor {
+	return func(spec *device.Spec) (proto.Control, error) {
+		read, err := spec.Duration("read-timeout", 0)
+		if err != nil {
+			return nil, err
+		}
+
+		write, err := spec.Duration("write-timeout", 0)
+		if err != nil {
+			return nil, err
+		}
+
+		reconnect, err := spec.Int("reconnect", 5)
+		if err != nil {
+			return nil, err
+		}
+
+		handshake, err := spec.Duration("handshake", 500*time.Millisecond)
+		if err != nil {
+			return nil, err
+		}
+
+		ack, err := spec.Duration("ack-wait", 0)
+		if err != nil {
+			return nil, err
+		}
+
+		chunk, err := spec.Int("chunk", 0)
+		if err != nil {
+			return nil, err
+		}
+
+		rate, err := spec.Int("rate", 0)
+		if err != nil {
+			return nil, err
+		}
+
+		format := model.Format
+		if name := spec.Get("format"); name != "" {
+			if format, err = bitmap.ParseFormat(name); err != nil {
+				return nil, err
+			}
+		}
+
+		serial := proto.NewSerial(spec.Target(),
+			proto.WithTimeout(read, write),
+			proto.WithReconnect(reconnect, time.Second),
+		)
+
+		return New(serial, spec.Logger, model,
+			WithHandshake(handshake),
+			WithAck(ack),
+			WithChunk(chunk),
+			WithRate(rate),
+			WithFormat(format),
+		)
+	}
+}
@@ -0,0 +1,35 @@
+package panel
+
+import (
+	"usbscreen/pkg/bitmap"
+)
+
+// Model describes what differs between the panels sharing this command set
+type Model struct {
+	Name   string
+	Width  int
+	Height int
+	// Orientations are the SetRotate codes of portrait, inverted portrait, landscape and inverted landscape
+	Orientations [4]uint8
+	// MaxLight is the darkest level the panel accepts, SetLight scales 0-255 into 0-MaxLight
+	MaxLight uint8
+	// BaudRate of the serial port
+	BaudRate int
+	// Format of the pixels in DrawBitmap and DrawPixels, zero is RGB565 in little endian
+	Format bitmap.Format
+}
+
+func (m *Model) orientation(landscape bool, invert bool) uint8 {
+	var i int
+	if landscape {
+		i += 2
+	}
+	if invert {
+		i++
+	}
+	return m.Orientations[i]
+}
+
+func (m *Model) light(light uint8) int {
+	return int(light) * int(m.MaxLight) / 255
+}
@@ -0,0 +1,378 @@
+package panel
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+
+	"usbscreen/pkg/bitmap"
+	"usbscreen/pkg/proto"
+)
+
+const (
+	Restart    = 101
+	Shutdown   = 108
+	Startup    = 109
+	SetLight   = 110
+	SetRotate  = 121
+	SetMirror  = 122
+	DrawPixels = 195
+	DrawBitmap = 197
+	TESTING    = 255
+)
+
+// maxPixels is the most coordinates one DrawPixels command could carry
+const maxPixels = 512
+
+func New(serial *proto.Serial, logger *zap.Logger, model *Model, opts ...Option) (proto.Control, error) {
+	dev := &Panel{
+		serial: serial,
+		logger: logger,
+		model:  model,
+		native: image.Pt(model.Width, model.Height),
+		width:  model.Width,
+		height: model.Height,
+		state:  state{powered: true},
+		format: model.Format,
+		// options
+		handshakeWait: 500 * time.Millisecond,
+	}
+
+	if logger == nil {
+		dev.logger = zap.NewNop()
+	}
+
+	for _, opt := range opts {
+		opt(dev)
+	}
+
+	serial.OnReconnect(dev.replay)
+
+	if err := serial.Open(&proto.Options{
+		DTR:      true,
+		RTS:      true,
+		BaudRate: model.BaudRate,
+	}); err != nil {
+		return nil, err
+	}
+
+	if err := dev.handshake(); err != nil {
+		_ = serial.Close()
+		return nil, err
+	}
+
+	return dev, nil
+}
+
+type Panel struct {
+	serial *proto.Serial
+	logger *zap.Logger
+	model  *Model
+	native image.Point
+	width  int
+	height int
+	sl     sync.Mutex // guards state, width and height
+	state  state
+	info   *Info
+	format bitmap.Format
+	rl     sync.Mutex
+	resets []func()
+	// options
+	handshakeWait time.Duration
+	ackWait       time.Duration
+	chunk         int
+	rate          int
+}
+
+// state is what has been sent to the panel, replayed after reconnecting
+type state struct {
+	powered   bool
+	lighted   bool
+	light     uint8
+	mirror    bool
+	landscape bool
+	invert    bool
+}
+
+func (p *Panel) Startup() error {
+	return p.StartupContext(context.Background())
+}
+
+func (p *Panel) StartupContext(ctx context.Context) error {
+	if err := p.sendCMD(ctx, Startup); err != nil {
+		return err
+	}
+
+	p.sl.Lock()
+	p.state.powered = true
+	p.sl.Unlock()
+	return nil
+}
+
+func (p *Panel) Shutdown() error {
+	return p.ShutdownContext(context.Background())
+}
+
+func (p *Panel) ShutdownContext(ctx context.Context) error {
+	if err := p.sendCMD(ctx, Shutdown); err != nil {
+		return err
+	}
+
+	p.sl.Lock()
+	p.state.powered = false
+	p.sl.Unlock()
+	return nil
+}
+
+func (p *Panel) Restart() error {
+	return p.RestartContext(context.Background())
+}
+
+func (p *Panel) RestartContext(ctx context.Context) error {
+	return p.sendCMD(ctx, Restart)
+}
+
+func (p *Panel) SetLight(light uint8) error {
+	return p.SetLightContext(context.Background(), light)
+}
+
+func (p *Panel) SetLightContext(ctx context.Context, light uint8) error {
+	if err := p.sendCMD(ctx, SetLight, p.model.light(light)); err != nil {
+		return err
+	}
+
+	p.sl.Lock()
+	p.state.lighted, p.state.light = true, light
+	p.sl.Unlock()
+	return nil
+}
+
+func (p *Panel) SetRotate(landscape bool, invert bool) error {
+	return p.SetRotateContext(context.Background(), landscape, invert)
+}
+
+func (p *Panel) SetRotateContext(ctx context.Context, landscape bool, invert bool) error {
+	bytes, size := p.packRotate(landscape, invert)
+	if err := p.sendBytes(ctx, bytes); err != nil {
+		return err
+	}
+
+	p.sl.Lock()
+	p.width, p.height = size.X, size.Y
+	p.state.landscape, p.state.invert = landscape, invert
+	p.sl.Unlock()
+	return nil
+}
+
+func (p *Panel) packRotate(landscape bool, invert bool) ([]byte, image.Point) {
+	size := p.native
+	if landscape {
+		size = image.Pt(size.Y, size.X)
+	}
+
+	var bs bytes.Buffer
+	bs.WriteByte(p.model.orientation(landscape, invert))
+	_ = binary.Write(&bs, binary.BigEndian, uint16(size.X))
+	_ = binary.Write(&bs, binary.BigEndian, uint16(size.Y))
+
+	packed, _ := packOpt(SetRotate, 16, bs.Bytes())
+	return packed, size
+}
+
+func (p *Panel) SetMirror(mirror bool) error {
+	return p.SetMirrorContext(context.Background(), mirror)
+}
+
+func (p *Panel) SetMirrorContext(ctx context.Context, mirror bool) error {
+	var b byte
+	if mirror {
+		b = 1
+	}
+
+	if err := p.sendOpt(ctx, SetMirror, 16, []byte{b}); err != nil {
+		return err
+	}
+
+	p.sl.Lock()
+	p.state.mirror = mirror
+	p.sl.Unlock()
+	return nil
+}
+
+// Close closes the port, the panel keeps showing the last frame
+func (p *Panel) Close() error {
+	return p.serial.Close()
+}
+
+// OnReset registers fn called when the port reopened, the panel is blank by then
+func (p *Panel) OnReset(fn func()) {
+	p.rl.Lock()
+	defer p.rl.Unlock()
+
+	p.resets = append(p.resets, fn)
+}
+
+func (p *Panel) State() (*proto.State, error) {
+	return p.StateContext(context.Background())
+}
+
+// StateContext answers from what has been sent, the panel is not queried so the light
+// is unknown until it has been set
+func (p *Panel) StateContext(ctx context.Context) (*proto.State, error) {
+	p.sl.Lock()
+	defer p.sl.Unlock()
+
+	state := &proto.State{
+		Powered:   p.state.powered,
+		Mirror:    p.state.mirror,
+		Landscape: p.state.landscape,
+		Invert:    p.state.invert,
+		Width:     p.width,
+		Height:    p.height,
+	}
+	if p.state.lighted {
+		light := p.state.light
+		state.Light = &light
+	}
+	return state, nil
+}
+
+// size is the resolution after rotation
+func (p *Panel) size() (int, int) {
+	p.sl.Lock()
+	defer p.sl.Unlock()
+
+	return p.width, p.height
+}
+
+func (p *Panel) DrawBitmap(posX uint16, posY uint16, image image.Image) error {
+	return p.DrawBitmapContext(context.Background(), posX, posY, image)
+}
+
+// DrawBitmapContext uploads the bitmap in strips of rows sized by the chunk option,
+// every strip is a complete command so stopping between them leaves the panel in sync.
+func (p *Panel) DrawBitmapContext(ctx context.Context, posX uint16, posY uint16, image image.Image) error {
+	rect := image.Bounds()
+	imgW, imgH := rect.Dx(), rect.Dy()
+
+	width, height := p.size()
+	if imgW+int(posX) > width {
+		return errors.New("width overflow")
+	} else if imgH+int(posY) > height {
+		return errors.New("height overflow")
+	}
+
+	bmp := bitmap.EncodeFormat(image, p.format)
+	pitch := imgW * p.format.Size()
+
+	rows := imgH
+	if p.chunk > 0 {
+		rows = p.chunk / pitch
+		if rows < 1 {
+			rows = 1
+		}
+	}
+
+	progress := proto.ProgressFrom(ctx)
+	start := time.Now()
+
+	for y := 0; y < imgH; y += rows {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		h := rows
+		if y+h > imgH {
+			h = imgH - y
+		}
+
+		top := int(posY) + y
+		head, err := packCMD(DrawBitmap, int(posX), top, int(posX)+imgW-1, top+h-1)
+		if err != nil {
+			return err
+		}
+
+		if err := p.sendBytes(ctx, head, bmp[y*pitch:(y+h)*pitch]); err != nil {
+			return err
+		}
+
+		sent := (y + h) * pitch
+		progress(sent, len(bmp))
+
+		if err := p.pacing(ctx, start, sent); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// pacing sleeps until sent bytes fit in the rate limit since start
+func (p *Panel) pacing(ctx context.Context, start time.Time, sent int) error {
+	if p.rate <= 0 {
+		return nil
+	}
+
+	wait := time.Duration(sent)*time.Second/time.Duration(p.rate) - time.Since(start)
+	if wait <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+func (p *Panel) DrawPixels(offsetX uint16, offsetY uint16, color color.Color, coordinates []uint8) error {
+	return p.DrawPixelsContext(context.Background(), offsetX, offsetY, color, coordinates)
+}
+
+func (p *Panel) DrawPixelsContext(ctx context.Context, offsetX uint16, offsetY uint16, color color.Color, coordinates []uint8) error {
+	if len(coordinates)%2 != 0 {
+		return errors.New("coordinates not paired")
+	}
+
+	width, height := p.size()
+	for n := 0; n < len(coordinates); n += 2 {
+		if int(offsetX)+int(coordinates[n]) >= width {
+			return errors.New("width overflow")
+		} else if int(offsetY)+int(coordinates[n+1]) >= height {
+			return errors.New("height overflow")
+		}
+	}
+
+	pixel := p.format.Pixel(color)
+	for len(coordinates) > 0 {
+		batch := coordinates
+		if len(batch) > maxPixels*2 {
+			batch = batch[:maxPixels*2]
+		}
+		coordinates = coordinates[len(batch):]
+
+		head, err := packCMD(DrawPixels, int(offsetX), int(offsetY), len(batch)/2)
+		if err != nil {
+			return err
+		}
+
+		// pixel color in the same format as DrawBitmap, then the x, y pairs
+		data := append(append([]byte{}, pixel...), batch...)
+		if err := p.sendBytes(ctx, head, data); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,91 @@
+package panel
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+
+	"usbscreen/pkg/bitmap"
+)
+
+// Info is the identification read back from the panel while opening. The protocol
+// carries no firmware version, ID is the reply as hex to tell revisions apart
+type Info struct {
+	Model string
+	ID    string
+	Raw   []byte
+}
+
+// models answers the handshake with 6 repeated bytes, older panels stay silent
+var models = map[byte]string{
+	0x01: "usbmonitor-3.5",
+	0x02: "usbmonitor-5",
+	0x03: "usbmonitor-7",
+}
+
+func parseInfo(resp []byte) *Info {
+	info := &Info{Model: "turing-3.5", Raw: resp}
+	if len(resp) == 0 {
+		return info
+	}
+
+	info.ID = fmt.Sprintf("%x", resp)
+	if model, ok := models[resp[0]]; ok && len(resp) == 6 && bytes.Count(resp, resp[:1]) == 6 {
+		info.Model = model
+	} else {
+		info.Model = "unknown"
+	}
+	return info
+}
+
+func (p *Panel) Info() *Info {
+	return p.info
+}
+
+// Format is the pixel format bitmaps are sent in
+func (p *Panel) Format() bitmap.Format {
+	return p.format
+}
+
+// handshake runs once while opening, not after reconnecting. It returns as soon as
+// the reply arrived, a silent panel costs the whole wait on every start
+func (p *Panel) handshake() error {
+	p.info = &Info{Model: "unknown"}
+	if p.handshakeWait <= 0 {
+		return nil
+	}
+
+	// written directly, the answer must not be taken as a write response
+	probe, _ := packCMD(TESTING)
+	if err := p.serial.Frame(probe); err != nil {
+		return errors.Wrap(err, "handshake failed")
+	}
+
+	resp := make([]byte, 6)
+	n, err := p.serial.ReadWait(resp, p.handshakeWait)
+	if err != nil {
+		return errors.Wrap(err, "handshake failed")
+	}
+
+	p.info = parseInfo(resp[:n])
+	p.logger.With(
+		zap.String("model", p.info.Model),
+		zap.String("id", p.info.ID),
+	).Info("handshake")
+
+	return nil
+}
+
+// response checks what the panel sent back after a write, it is silent on success
+func (p *Panel) response() error {
+	resp := make([]byte, 64)
+	n, err := p.serial.ReadWait(resp, p.ackWait)
+	if err != nil {
+		return errors.Wrap(err, "read response failed")
+	} else if n > 0 {
+		return errors.Errorf("device responded %x", resp[:n])
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+package panel
+
+import (
+	"time"
+
+	"usbscreen/pkg/bitmap"
+)
+
+type Option func(p *Panel)
+
+// WithHandshake sets how long to wait for the identification while opening, 500ms by default.
+// Older panels never answer so opening them takes that long, 0 skips the handshake
+func WithHandshake(wait time.Duration) Option {
+	return func(p *Panel) {
+		p.handshakeWait = wait
+	}
+}
+
+// WithChunk splits bitmaps into strips of about size bytes, 0 sends them at once
+func WithChunk(size int) Option {
+	return func(p *Panel) {
+		p.chunk = size
+	}
+}
+
+// WithRate limits bitmap uploads to bytes per second, 0 is unlimited
+func WithRate(bytes int) Option {
+	return func(p *Panel) {
+		p.rate = bytes
+	}
+}
+
+// WithAck waits for the panel response after every write and fails if it reports anything
+func WithAck(wait time.Duration) Option {
+	return func(p *Panel) {
+		p.ackWait = wait
+	}
+}
+
+// WithFormat overrides the pixel format declared by the model
+func WithFormat(f bitmap.Format) Option {
+	return func(p *Panel) {
+		p.format = f
+	}
+}
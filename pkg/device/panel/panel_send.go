@@ -0,0 +1,144 @@
+package panel
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/samber/lo"
+	"go.uber.org/zap"
+)
+
+func (p *Panel) sendCMD(ctx context.Context, code uint8, vars ...int) error {
+	bytes, err := packCMD(code, vars...)
+	if err != nil {
+		return err
+	}
+
+	return p.sendBytes(ctx, bytes)
+}
+
+func (p *Panel) sendOpt(ctx context.Context, code uint8, fixed int, bytes []byte) error {
+	bytes, err := packOpt(code, fixed, bytes)
+	if err != nil {
+		return err
+	}
+
+	return p.sendBytes(ctx, bytes)
+}
+
+func packCMD(code uint8, vars ...int) ([]byte, error) {
+	if len(vars) > 4 {
+		return nil, errors.New("too many vars")
+	}
+
+	var vars2 [4]int
+	for i, v := range vars {
+		vars2[i] = v
+	}
+
+	return packRaw(code, vars2[0], vars2[1], vars2[2], vars2[3], nil), nil
+}
+
+func packOpt(code uint8, fixed int, bytes []byte) ([]byte, error) {
+	if len(bytes) > fixed {
+		return nil, errors.New("too many bytes")
+	}
+
+	bytes = append(make([]byte, 6), bytes...)
+	if len(bytes) < fixed {
+		bytes = append(bytes, make([]byte, fixed-len(bytes))...)
+	}
+
+	return packRaw(code, 0, 0, 0, 0, bytes), nil
+}
+
+func packRaw(code uint8, var1 int, var2 int, var3 int, var4 int, bytes []byte) []byte {
+	if len(bytes) == 0 {
+		bytes = make([]byte, 6)
+	}
+
+	bytes[0] = (byte)(var1 >> 2)
+	bytes[1] = (byte)(((var1 & 3) << 6) + (var2 >> 4))
+	bytes[2] = (byte)(((var2 & 0xF) << 4) + (var3 >> 6))
+	bytes[3] = (byte)(((var3 & 0x3F) << 2) + (var4 >> 8))
+	bytes[4] = (byte)(var4 & 0xFF)
+	bytes[5] = code
+
+	return bytes
+}
+
+// sendBytes writes all frames as one unit, they are resent together after a reconnect
+func (p *Panel) sendBytes(ctx context.Context, frames ...[]byte) error {
+	var sent int
+	var cost time.Duration
+
+	start := time.Now()
+	if err := p.serial.FrameContext(ctx, frames...); err != nil {
+		return err
+	} else {
+		for _, bytes := range frames {
+			sent += len(bytes)
+		}
+		cost = time.Since(start)
+	}
+
+	if p.ackWait > 0 {
+		if err := p.response(); err != nil {
+			return err
+		}
+	}
+
+	ext := ""
+	if sent <= 16 {
+		for _, bytes := range frames {
+			ext += fmt.Sprintf("%x", bytes)
+		}
+	}
+
+	p.logger.With(
+		zap.Int("sent", sent),
+		zap.String("cost", cost.String()),
+		zap.String("data", ext),
+	).Debug("transfer")
+
+	return nil
+}
+
+// replay restores the tracked states on a reopened port
+func (p *Panel) replay(w io.Writer) error {
+	p.rl.Lock()
+	for _, fn := range p.resets {
+		fn()
+	}
+	p.rl.Unlock()
+
+	p.sl.Lock()
+	state := p.state
+	p.sl.Unlock()
+
+	frames := make([][]byte, 0, 4)
+
+	power, _ := packCMD(lo.Ternary(state.powered, uint8(Startup), uint8(Shutdown)))
+	frames = append(frames, power)
+
+	if state.lighted {
+		light, _ := packCMD(SetLight, p.model.light(state.light))
+		frames = append(frames, light)
+	}
+
+	rotate, _ := p.packRotate(state.landscape, state.invert)
+	mirror, _ := packOpt(SetMirror, 16, []byte{lo.Ternary[byte](state.mirror, 1, 0)})
+	frames = append(frames, rotate, mirror)
+
+	for _, bytes := range frames {
+		if _, err := w.Write(bytes); err != nil {
+			return err
+		}
+	}
+
+	p.logger.Info("states replayed")
+	return nil
+}
@@ -0,0 +1,167 @@
+package device
+
+import (
+	"image"
+	"io"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+	"go.uber.org/multierr"
+	"go.uber.org/zap"
+
+	"usbscreen/pkg/bitmap"
+	"usbscreen/pkg/proto"
+)
+
+type Constructor func(spec *Spec) (proto.Control, error)
+
+// Driver is a kind of device opened by the spec scheme, Width and Height are the native resolution
+// in portrait, zero if it is only known once opened like a remote panel
+type Driver struct {
+	Name   string
+	New    Constructor
+	Width  int
+	Height int
+}
+
+var (
+	dl      sync.RWMutex
+	drivers = make(map[string]*Driver)
+)
+
+// Register makes a driver available by name, it panics if the name registered twice
+func Register(d *Driver) {
+	dl.Lock()
+	defer dl.Unlock()
+
+	if _, exists := drivers[d.Name]; exists {
+		panic("device: register driver twice for " + d.Name)
+	}
+	drivers[d.Name] = d
+}
+
+func Lookup(name string) (*Driver, bool) {
+	dl.RLock()
+	defer dl.RUnlock()
+
+	d, ok := drivers[name]
+	return d, ok
+}
+
+// Drivers returns the sorted names of registered drivers
+func Drivers() []string {
+	dl.RLock()
+	defer dl.RUnlock()
+
+	names := make([]string, 0, len(drivers))
+	for name := range drivers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Native returns the native resolution of the driver opening spec, false if it has none
+func Native(spec string) (image.Point, bool) {
+	u, err := Parse(spec)
+	if err != nil {
+		return image.Point{}, false
+	}
+
+	d, ok := Lookup(u.Scheme)
+	if !ok || d.Width <= 0 || d.Height <= 0 {
+		return image.Point{}, false
+	}
+	return image.Pt(d.Width, d.Height), true
+}
+
+// Open creates the device by spec like inch35:///dev/ttyACM0, remote://host:9123 or mock://,
+// defaults are used for the query values absent in spec. Any spec takes profile=<file> to
+// calibrate the colors, see bitmap.Profile.
+func Open(spec string, logger *zap.Logger, defaults url.Values) (proto.Control, error) {
+	u, err := Parse(spec)
+	if err != nil {
+		return nil, err
+	}
+
+	d, ok := Lookup(u.Scheme)
+	if !ok {
+		return nil, errors.Errorf("unknown device %q, supported %s", u.Scheme, strings.Join(Drivers(), ", "))
+	}
+
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+
+	dev, err := d.New(&Spec{
+		URL:      u,
+		Logger:   logger.With(zap.String("device", d.Name)),
+		Defaults: defaults,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// only taken from the spec itself, so nested devices are not calibrated twice
+	if file := u.Query().Get("profile"); file != "" {
+		profile, err := bitmap.LoadProfile(file)
+		if err != nil {
+			_ = Close(dev)
+			return nil, err
+		}
+
+		cal, err := Calibrate(dev, profile)
+		if err != nil {
+			_ = Close(dev)
+			return nil, err
+		}
+		return cal, nil
+	}
+
+	return dev, nil
+}
+
+// Close releases the ports or connections held by devs, others need no closing
+func Close(devs ...proto.Control) error {
+	var err error
+	for _, dev := range devs {
+		if c, ok := dev.(io.Closer); ok {
+			err = multierr.Append(err, c.Close())
+		}
+	}
+	return err
+}
+
+// Parse parses the device spec, the legacy forms are still accepted:
+// mock, <host>:<port> for remote, usb:VID:PID or a port name for inch35
+func Parse(spec string) (*url.URL, error) {
+	if strings.Contains(spec, "://") {
+		return parse(spec)
+	} else if i := strings.Index(spec, ":"); i > 0 {
+		if _, ok := Lookup(spec[:i]); ok {
+			return parse(spec)
+		}
+	}
+
+	switch {
+	case spec == "mock":
+		spec = "mock://"
+	case strings.HasPrefix(spec, "usb:"), !strings.Contains(spec, ":"):
+		spec = "inch35:" + spec
+	default:
+		spec = "remote://" + spec
+	}
+
+	return parse(spec)
+}
+
+func parse(spec string) (*url.URL, error) {
+	u, err := url.Parse(spec)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid device %q", spec)
+	}
+	return u, nil
+}
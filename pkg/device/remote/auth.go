@@ -0,0 +1,39 @@
+package remote
+
+import (
+	"crypto/subtle"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Auth rejects requests without one of the tokens, no tokens allows everyone
+type Auth struct {
+	Tokens []string
+}
+
+func (a *Auth) Handler(next http.Handler) http.Handler {
+	if a == nil || len(a.Tokens) == 0 {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if !a.allowed(strings.TrimPrefix(req.Header.Get("Authorization"), "Bearer ")) {
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = io.WriteString(w, "401 unauthorized\n")
+			return
+		}
+		next.ServeHTTP(w, req)
+	})
+}
+
+func (a *Auth) allowed(token string) bool {
+	var ok bool
+	for _, t := range a.Tokens {
+		if subtle.ConstantTimeCompare([]byte(t), []byte(token)) == 1 {
+			ok = true
+		}
+	}
+	return ok
+}
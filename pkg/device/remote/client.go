@@ -1,63 +1,217 @@
 package remote
 
 import (
+	"bufio"
 	"bytes"
+	"context"
+	"crypto/tls"
 	"image"
+	"image/color"
 	"image/png"
+	"io"
+	"net"
+	"net/http"
 	"net/rpc"
 
+	"github.com/pkg/errors"
+
 	"usbscreen/pkg/proto"
 )
 
-func New(addr string) (proto.Control, error) {
-	client, err := rpc.DialHTTP("tcp", addr)
+// rpcConnected is the response of net/rpc for HTTP CONNECT
+const rpcConnected = "200 Connected to Go RPC"
+
+var (
+	errUnauthorized = errors.New("unauthorized")
+	errUnsupported  = errors.New("protocol unsupported")
+)
+
+// New prefers the stream protocol and falls back to net/rpc for older servers
+func New(addr string, opts ...Option) (proto.Control, error) {
+	stream, err := DialStream(addr, opts...)
+	if err == nil {
+		return stream, nil
+	} else if !errors.Is(err, errUnsupported) {
+		return nil, err
+	}
+
+	conn, err := connect(addr, rpc.DefaultRPCPath, rpcConnected, newOptions(opts))
 	if err != nil {
 		return nil, err
 	}
 
-	return &Client{rpc: client}, nil
+	return &Client{rpc: rpc.NewClient(conn)}, nil
+}
+
+// connect dials addr and switches to the protocol served at path by HTTP CONNECT
+func connect(addr, path, connected string, o *options) (net.Conn, error) {
+	var conn net.Conn
+	var err error
+	if o.tls != nil {
+		conn, err = tls.Dial("tcp", addr, o.tls)
+	} else {
+		conn, err = net.Dial("tcp", addr)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	req := "CONNECT " + path + " HTTP/1.0\n"
+	if o.token != "" {
+		req += "Authorization: Bearer " + o.token + "\n"
+	}
+
+	if _, err = io.WriteString(conn, req+"\n"); err == nil {
+		r := bufio.NewReader(conn)
+		var resp *http.Response
+		if resp, err = http.ReadResponse(r, &http.Request{Method: "CONNECT"}); err == nil {
+			if resp.Status == connected {
+				return &bufConn{Conn: conn, r: r}, nil
+			} else if resp.StatusCode == http.StatusUnauthorized {
+				err = errUnauthorized
+			} else {
+				err = errors.Wrap(errUnsupported, resp.Status)
+			}
+		}
+	}
+
+	_ = conn.Close()
+	return nil, err
+}
+
+// bufConn keeps reading from the buffer used for the CONNECT response
+type bufConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+func (c *bufConn) Read(p []byte) (int, error) {
+	return c.r.Read(p)
 }
 
 type Client struct {
 	rpc *rpc.Client
 }
 
+func (c *Client) Close() error {
+	return c.rpc.Close()
+}
+
 func (c *Client) Startup() error {
-	return c.rpc.Call("Service.Command", "startup", nil)
+	return c.StartupContext(context.Background())
+}
+
+func (c *Client) StartupContext(ctx context.Context) error {
+	return c.call(ctx, "Service.Command", "startup", nil)
 }
 
 func (c *Client) Shutdown() error {
-	return c.rpc.Call("Service.Command", "shutdown", nil)
+	return c.ShutdownContext(context.Background())
+}
+
+func (c *Client) ShutdownContext(ctx context.Context) error {
+	return c.call(ctx, "Service.Command", "shutdown", nil)
 }
 
 func (c *Client) Restart() error {
-	return c.rpc.Call("Service.Command", "restart", nil)
+	return c.RestartContext(context.Background())
+}
+
+func (c *Client) RestartContext(ctx context.Context) error {
+	return c.call(ctx, "Service.Command", "restart", nil)
 }
 
 func (c *Client) SetLight(light uint8) error {
-	return c.rpc.Call("Service.SetLight", light, nil)
+	return c.SetLightContext(context.Background(), light)
+}
+
+func (c *Client) SetLightContext(ctx context.Context, light uint8) error {
+	return c.call(ctx, "Service.SetLight", light, nil)
 }
 
 func (c *Client) SetMirror(mirror bool) error {
-	return c.rpc.Call("Service.SetMirror", mirror, nil)
+	return c.SetMirrorContext(context.Background(), mirror)
+}
+
+func (c *Client) SetMirrorContext(ctx context.Context, mirror bool) error {
+	return c.call(ctx, "Service.SetMirror", mirror, nil)
 }
 
 func (c *Client) SetRotate(landscape bool, invert bool) error {
-	return c.rpc.Call("Service.SetRotate", SetRotateRequest{
+	return c.SetRotateContext(context.Background(), landscape, invert)
+}
+
+func (c *Client) SetRotateContext(ctx context.Context, landscape bool, invert bool) error {
+	return c.call(ctx, "Service.SetRotate", SetRotateRequest{
 		Landscape: landscape,
 		Invert:    invert,
 	}, nil)
 }
 
 func (c *Client) DrawBitmap(posX uint16, posY uint16, image image.Image) error {
+	return c.DrawBitmapContext(context.Background(), posX, posY, image)
+}
+
+func (c *Client) DrawBitmapContext(ctx context.Context, posX uint16, posY uint16, image image.Image) error {
 	var buf bytes.Buffer
 	if err := png.Encode(&buf, image); err != nil {
 		return err
 	}
 
-	return c.rpc.Call("Service.DrawBitmap", &DrawBitmapRequest{
+	if err := c.call(ctx, "Service.DrawBitmap", &DrawBitmapRequest{
 		PosX:  posX,
 		PosY:  posY,
 		Image: buf.Bytes(),
+	}, nil); err != nil {
+		return err
+	}
+
+	total := image.Bounds().Dx() * image.Bounds().Dy() * 2
+	proto.ProgressFrom(ctx)(total, total)
+	return nil
+}
+
+func (c *Client) DrawPixels(offsetX uint16, offsetY uint16, color color.Color, coordinates []uint8) error {
+	return c.DrawPixelsContext(context.Background(), offsetX, offsetY, color, coordinates)
+}
+
+func (c *Client) DrawPixelsContext(ctx context.Context, offsetX uint16, offsetY uint16, color color.Color, coordinates []uint8) error {
+	return c.call(ctx, "Service.DrawPixels", &DrawPixelsRequest{
+		OffsetX:     offsetX,
+		OffsetY:     offsetY,
+		Color:       toRGBA64(color),
+		Coordinates: coordinates,
 	}, nil)
 }
+
+func toRGBA64(c color.Color) color.RGBA64 {
+	r, g, b, a := c.RGBA()
+	return color.RGBA64{R: uint16(r), G: uint16(g), B: uint16(b), A: uint16(a)}
+}
+
+func (c *Client) State() (*proto.State, error) {
+	return c.StateContext(context.Background())
+}
+
+func (c *Client) StateContext(ctx context.Context) (*proto.State, error) {
+	var state proto.State
+	if err := c.call(ctx, "Service.State", EmptyRequest{}, &state); err != nil {
+		return nil, err
+	}
+	return &state, nil
+}
+
+// call stops waiting once ctx is done, the late reply is dropped by net/rpc
+func (c *Client) call(ctx context.Context, method string, args any, reply any) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	call := c.rpc.Go(method, args, reply, make(chan *rpc.Call, 1))
+	select {
+	case <-call.Done:
+		return call.Error
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,41 @@
+package remote
+
+import (
+	"github.com/pkg/errors"
+
+	"usbscreen/pkg/device"
+	"usbscreen/pkg/proto"
+)
+
+func init() {
+	device.Register(&device.Driver{
+		Name: "remote",
+		New:  open,
+	})
+}
+
+// open accepts remote://host:9123 with query token, ca, cert, key and encoding (raw or delta)
+func open(spec *device.Spec) (proto.Control, error) {
+	var opts []Option
+	if token := spec.Get("token"); token != "" {
+		opts = append(opts, WithToken(token))
+	}
+
+	if ca, cert := spec.Get("ca"), spec.Get("cert"); ca != "" || cert != "" {
+		cfg, err := ClientTLS(ca, cert, spec.Get("key"))
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, WithTLS(cfg))
+	}
+
+	switch enc := spec.Get("encoding"); enc {
+	case "", "delta":
+	case "raw":
+		opts = append(opts, WithEncoding(EncodingRaw))
+	default:
+		return nil, errors.Errorf("unknown encoding %q", enc)
+	}
+
+	return New(spec.Target(), opts...)
+}
@@ -0,0 +1,106 @@
+package remote
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"os"
+
+	"github.com/pkg/errors"
+)
+
+type Option func(o *options)
+
+type options struct {
+	token    string
+	tls      *tls.Config
+	encoding uint8
+}
+
+// WithToken authenticates to the server with a bearer token
+func WithToken(token string) Option {
+	return func(o *options) {
+		o.token = token
+	}
+}
+
+// WithTLS connects over TLS, include a client certificate for mutual TLS
+func WithTLS(cfg *tls.Config) Option {
+	return func(o *options) {
+		o.tls = cfg
+	}
+}
+
+// WithEncoding sets the bitmap encoding of the stream protocol
+func WithEncoding(encoding uint8) Option {
+	return func(o *options) {
+		o.encoding = encoding
+	}
+}
+
+func newOptions(opts []Option) *options {
+	o := &options{encoding: EncodingDelta}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// ClientTLS builds the client config, ca verifies the server and cert/key is the client certificate, all optional
+func ClientTLS(ca, cert, key string) (*tls.Config, error) {
+	cfg := &tls.Config{MinVersion: tls.VersionTLS12}
+
+	if ca != "" {
+		pool, err := loadPool(ca)
+		if err != nil {
+			return nil, err
+		}
+		cfg.RootCAs = pool
+	}
+
+	if cert != "" {
+		pair, err := tls.LoadX509KeyPair(cert, key)
+		if err != nil {
+			return nil, errors.Wrap(err, "load client certificate failed")
+		}
+		cfg.Certificates = []tls.Certificate{pair}
+	}
+
+	return cfg, nil
+}
+
+// ServerTLS builds the server config, client certificates are required once clientCA is set
+func ServerTLS(cert, key, clientCA string) (*tls.Config, error) {
+	pair, err := tls.LoadX509KeyPair(cert, key)
+	if err != nil {
+		return nil, errors.Wrap(err, "load server certificate failed")
+	}
+
+	cfg := &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		Certificates: []tls.Certificate{pair},
+	}
+
+	if clientCA != "" {
+		pool, err := loadPool(clientCA)
+		if err != nil {
+			return nil, err
+		}
+		cfg.ClientCAs = pool
+		cfg.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	return cfg, nil
+}
+
+func loadPool(file string) (*x509.CertPool, error) {
+	pem, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, errors.Errorf("no certificates in %s", file)
+	}
+	return pool, nil
+}
@@ -3,9 +3,11 @@ package remote
 import (
 	"bytes"
 	"context"
+	"image"
 	"image/png"
 	"net/http"
 	"net/rpc"
+	"sync"
 
 	"github.com/pkg/errors"
 	"go.uber.org/fx"
@@ -13,18 +15,31 @@ import (
 	"usbscreen/pkg/proto"
 )
 
-func Proxy(dev proto.Control, srv *http.Server, lifecycle fx.Lifecycle) error {
+// Proxy serves dev on srv, it listens with TLS if srv.TLSConfig has certificates
+func Proxy(dev proto.Control, srv *http.Server, auth *Auth, lifecycle fx.Lifecycle) error {
 	svc := &Service{dev: dev}
 	if err := rpc.Register(svc); err != nil {
 		return err
 	}
 
 	rpc.HandleHTTP()
+	http.Handle(StreamPath, &streamHandler{svc: svc})
+
+	if srv.Handler == nil {
+		srv.Handler = http.DefaultServeMux
+	}
+	srv.Handler = auth.Handler(srv.Handler)
 
 	lifecycle.Append(fx.Hook{
 		OnStart: func(ctx context.Context) error {
 			go func() {
-				if err := srv.ListenAndServe(); err != http.ErrServerClosed {
+				var err error
+				if srv.TLSConfig != nil && len(srv.TLSConfig.Certificates) > 0 {
+					err = srv.ListenAndServeTLS("", "")
+				} else {
+					err = srv.ListenAndServe()
+				}
+				if err != http.ErrServerClosed {
 					panic(err)
 				}
 			}()
@@ -38,11 +53,16 @@ func Proxy(dev proto.Control, srv *http.Server, lifecycle fx.Lifecycle) error {
 	return nil
 }
 
+// Service serves both net/rpc and stream clients, calls to the device are serialized
 type Service struct {
+	l   sync.Mutex
 	dev proto.Control
 }
 
 func (s *Service) Command(name string, _ *EmptyResponse) error {
+	s.l.Lock()
+	defer s.l.Unlock()
+
 	switch name {
 	case "startup":
 		return s.dev.Startup()
@@ -56,14 +76,23 @@ func (s *Service) Command(name string, _ *EmptyResponse) error {
 }
 
 func (s *Service) SetLight(light uint8, _ *EmptyResponse) error {
+	s.l.Lock()
+	defer s.l.Unlock()
+
 	return s.dev.SetLight(light)
 }
 
 func (s *Service) SetMirror(mirror bool, _ *EmptyResponse) error {
+	s.l.Lock()
+	defer s.l.Unlock()
+
 	return s.dev.SetMirror(mirror)
 }
 
 func (s *Service) SetRotate(req SetRotateRequest, _ *EmptyResponse) error {
+	s.l.Lock()
+	defer s.l.Unlock()
+
 	return s.dev.SetRotate(req.Landscape, req.Invert)
 }
 
@@ -73,5 +102,32 @@ func (s *Service) DrawBitmap(req *DrawBitmapRequest, _ *EmptyResponse) error {
 		return err
 	}
 
-	return s.dev.DrawBitmap(req.PosX, req.PosY, img)
+	return s.drawBitmap(req.PosX, req.PosY, img)
+}
+
+func (s *Service) drawBitmap(posX uint16, posY uint16, img image.Image) error {
+	s.l.Lock()
+	defer s.l.Unlock()
+
+	return s.dev.DrawBitmap(posX, posY, img)
+}
+
+func (s *Service) DrawPixels(req *DrawPixelsRequest, _ *EmptyResponse) error {
+	s.l.Lock()
+	defer s.l.Unlock()
+
+	return s.dev.DrawPixels(req.OffsetX, req.OffsetY, req.Color, req.Coordinates)
+}
+
+func (s *Service) State(_ EmptyRequest, resp *proto.State) error {
+	s.l.Lock()
+	defer s.l.Unlock()
+
+	state, err := s.dev.State()
+	if err != nil {
+		return err
+	}
+
+	*resp = *state
+	return nil
 }
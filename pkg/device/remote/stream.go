@@ -0,0 +1,212 @@
+package remote
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"io"
+
+	"github.com/pkg/errors"
+
+	"usbscreen/pkg/proto"
+)
+
+// StreamPath is the HTTP CONNECT path switching to the binary stream protocol,
+// served on the same listener as the net/rpc Service.
+const StreamPath = "/_usbscreen_stream_"
+
+const streamConnected = "200 Connected to usbscreen stream"
+
+// stream operations
+const (
+	opStartup uint8 = iota + 1
+	opShutdown
+	opRestart
+	opSetLight
+	opSetMirror
+	opSetRotate
+	opDrawBitmap
+	opDrawPixels
+	opState
+)
+
+// bitmap encodings
+const (
+	// EncodingRaw sends RGB565 pixels as is
+	EncodingRaw uint8 = iota
+	// EncodingDelta sends deflated RGB565 pixels xor-ed with what was sent before at the same place
+	EncodingDelta
+)
+
+// encodingReset is a flag on the encoding, both ends clear their canvas before the bitmap.
+// The client sets it after any failed bitmap, the server refuses deltas until then.
+const encodingReset uint8 = 0x80
+
+// stateNoLight is a flag on the powered byte of a state, the light byte is then unknown
+const stateNoLight uint8 = 0x80
+
+const maxPayload = 16 << 20
+
+// frame is both the request (code is op) and the response (code is status)
+type frame struct {
+	id      uint32
+	code    uint8
+	payload []byte
+}
+
+func writeFrame(w io.Writer, f *frame) error {
+	var head [9]byte
+	binary.BigEndian.PutUint32(head[0:4], f.id)
+	head[4] = f.code
+	binary.BigEndian.PutUint32(head[5:9], uint32(len(f.payload)))
+
+	if _, err := w.Write(head[:]); err != nil {
+		return err
+	}
+	_, err := w.Write(f.payload)
+	return err
+}
+
+func readFrame(r io.Reader) (*frame, error) {
+	var head [9]byte
+	if _, err := io.ReadFull(r, head[:]); err != nil {
+		return nil, err
+	}
+
+	size := binary.BigEndian.Uint32(head[5:9])
+	if size > maxPayload {
+		return nil, errors.New("payload too large")
+	}
+
+	f := &frame{
+		id:      binary.BigEndian.Uint32(head[0:4]),
+		code:    head[4],
+		payload: make([]byte, size),
+	}
+	if _, err := io.ReadFull(r, f.payload); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// canvas mirrors the pixels sent through a stream, both ends keep one to compute deltas
+type canvas struct {
+	pix    []byte
+	width  int
+	height int
+}
+
+// xor returns data xor-ed with the stored region at x, y
+func (c *canvas) xor(x, y, w, h int, data []byte) []byte {
+	c.grow(x+w, y+h)
+
+	out := make([]byte, len(data))
+	for row := 0; row < h; row++ {
+		i := ((y+row)*c.width + x) * 2
+		j := row * w * 2
+		for k := 0; k < w*2; k++ {
+			out[j+k] = data[j+k] ^ c.pix[i+k]
+		}
+	}
+	return out
+}
+
+// put stores the pixels of region at x, y
+func (c *canvas) put(x, y, w, h int, pix []byte) {
+	c.grow(x+w, y+h)
+
+	for row := 0; row < h; row++ {
+		i := ((y+row)*c.width + x) * 2
+		copy(c.pix[i:i+w*2], pix[row*w*2:(row+1)*w*2])
+	}
+}
+
+func (c *canvas) reset() {
+	c.pix, c.width, c.height = nil, 0, 0
+}
+
+func (c *canvas) grow(w, h int) {
+	if w <= c.width && h <= c.height {
+		return
+	}
+
+	if w < c.width {
+		w = c.width
+	}
+	if h < c.height {
+		h = c.height
+	}
+
+	pix := make([]byte, w*h*2)
+	for row := 0; row < c.height; row++ {
+		copy(pix[row*w*2:], c.pix[row*c.width*2:(row+1)*c.width*2])
+	}
+	c.pix, c.width, c.height = pix, w, h
+}
+
+func encodeState(s *proto.State) []byte {
+	b := make([]byte, 9)
+	b[0], b[2], b[3], b[4] = boolByte(s.Powered), boolByte(s.Mirror), boolByte(s.Landscape), boolByte(s.Invert)
+	if s.Light != nil {
+		b[1] = *s.Light
+	} else {
+		b[0] |= stateNoLight
+	}
+	binary.BigEndian.PutUint16(b[5:7], uint16(s.Width))
+	binary.BigEndian.PutUint16(b[7:9], uint16(s.Height))
+	return b
+}
+
+func decodeState(b []byte) (*proto.State, error) {
+	if len(b) != 9 {
+		return nil, errors.New("invalid state")
+	}
+
+	state := &proto.State{
+		Powered:   b[0]&^stateNoLight != 0,
+		Mirror:    b[2] != 0,
+		Landscape: b[3] != 0,
+		Invert:    b[4] != 0,
+		Width:     int(binary.BigEndian.Uint16(b[5:7])),
+		Height:    int(binary.BigEndian.Uint16(b[7:9])),
+	}
+	if b[0]&stateNoLight == 0 {
+		light := b[1]
+		state.Light = &light
+	}
+	return state, nil
+}
+
+func boolByte(b bool) byte {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func deflate(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w, err := flate.NewWriter(&buf, flate.BestSpeed)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// inflate decompresses exactly size bytes, more data is an error rather than read
+func inflate(data []byte, size int) ([]byte, error) {
+	out, err := io.ReadAll(io.LimitReader(flate.NewReader(bytes.NewReader(data)), int64(size)+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(out) != size {
+		return nil, errors.Errorf("inflated %d bytes, expected %d", len(out), size)
+	}
+	return out, nil
+}
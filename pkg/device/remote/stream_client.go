@@ -0,0 +1,295 @@
+package remote
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"usbscreen/pkg/bitmap"
+	"usbscreen/pkg/proto"
+)
+
+// DialStream connects with the binary stream protocol, requests could be pipelined
+// by concurrent callers and each one gets its own error.
+func DialStream(addr string, opts ...Option) (*Stream, error) {
+	o := newOptions(opts)
+
+	conn, err := connect(addr, StreamPath, streamConnected, o)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Stream{
+		conn:    conn,
+		enc:     o.encoding,
+		w:       bufio.NewWriter(conn),
+		pending: make(map[uint32]chan result),
+	}
+	go s.reading(conn)
+	return s, nil
+}
+
+type Stream struct {
+	conn net.Conn
+	enc  uint8
+	// writing side, also guards the shadow canvas so it follows the stream order
+	wl  sync.Mutex
+	w   *bufio.Writer
+	seq uint32
+	// shadow is what the server holds once every bitmap sent was acknowledged,
+	// resync clears it on both ends with the next bitmap after one failed
+	shadow canvas
+	resync bool
+	// waiting calls
+	pl      sync.Mutex
+	pending map[uint32]chan result
+	err     error
+}
+
+type result struct {
+	payload []byte
+	err     error
+}
+
+func (s *Stream) Close() error {
+	return s.conn.Close()
+}
+
+func (s *Stream) Startup() error {
+	return s.StartupContext(context.Background())
+}
+
+func (s *Stream) StartupContext(ctx context.Context) error {
+	return s.call(ctx, opStartup, nil)
+}
+
+func (s *Stream) Shutdown() error {
+	return s.ShutdownContext(context.Background())
+}
+
+func (s *Stream) ShutdownContext(ctx context.Context) error {
+	return s.call(ctx, opShutdown, nil)
+}
+
+func (s *Stream) Restart() error {
+	return s.RestartContext(context.Background())
+}
+
+func (s *Stream) RestartContext(ctx context.Context) error {
+	return s.call(ctx, opRestart, nil)
+}
+
+func (s *Stream) SetLight(light uint8) error {
+	return s.SetLightContext(context.Background(), light)
+}
+
+func (s *Stream) SetLightContext(ctx context.Context, light uint8) error {
+	return s.call(ctx, opSetLight, func() ([]byte, error) {
+		return []byte{light}, nil
+	})
+}
+
+func (s *Stream) SetMirror(mirror bool) error {
+	return s.SetMirrorContext(context.Background(), mirror)
+}
+
+func (s *Stream) SetMirrorContext(ctx context.Context, mirror bool) error {
+	return s.call(ctx, opSetMirror, func() ([]byte, error) {
+		return []byte{boolByte(mirror)}, nil
+	})
+}
+
+func (s *Stream) SetRotate(landscape bool, invert bool) error {
+	return s.SetRotateContext(context.Background(), landscape, invert)
+}
+
+func (s *Stream) SetRotateContext(ctx context.Context, landscape bool, invert bool) error {
+	return s.call(ctx, opSetRotate, func() ([]byte, error) {
+		return []byte{boolByte(landscape), boolByte(invert)}, nil
+	})
+}
+
+func (s *Stream) DrawBitmap(posX uint16, posY uint16, image image.Image) error {
+	return s.DrawBitmapContext(context.Background(), posX, posY, image)
+}
+
+func (s *Stream) DrawBitmapContext(ctx context.Context, posX uint16, posY uint16, image image.Image) error {
+	b := image.Bounds()
+	pix := bitmap.Encode(image)
+
+	err := s.call(ctx, opDrawBitmap, func() ([]byte, error) {
+		x, y, w, h := int(posX), int(posY), b.Dx(), b.Dy()
+
+		enc := s.enc
+		if s.resync {
+			s.shadow.reset()
+			s.resync = false
+			enc |= encodingReset
+		}
+
+		data := pix
+		if s.enc == EncodingDelta {
+			var err error
+			if data, err = deflate(s.shadow.xor(x, y, w, h, pix)); err != nil {
+				return nil, err
+			}
+		}
+		s.shadow.put(x, y, w, h, pix)
+
+		head := make([]byte, 9, 9+len(data))
+		binary.BigEndian.PutUint16(head[0:2], posX)
+		binary.BigEndian.PutUint16(head[2:4], posY)
+		binary.BigEndian.PutUint16(head[4:6], uint16(w))
+		binary.BigEndian.PutUint16(head[6:8], uint16(h))
+		head[8] = enc
+		return append(head, data...), nil
+	})
+	if err != nil {
+		// the server may not have applied it, later deltas are refused until the reset
+		s.wl.Lock()
+		s.resync = true
+		s.wl.Unlock()
+		return err
+	}
+
+	total := len(pix)
+	proto.ProgressFrom(ctx)(total, total)
+	return nil
+}
+
+func (s *Stream) DrawPixels(offsetX uint16, offsetY uint16, color color.Color, coordinates []uint8) error {
+	return s.DrawPixelsContext(context.Background(), offsetX, offsetY, color, coordinates)
+}
+
+func (s *Stream) DrawPixelsContext(ctx context.Context, offsetX uint16, offsetY uint16, color color.Color, coordinates []uint8) error {
+	return s.call(ctx, opDrawPixels, func() ([]byte, error) {
+		c := toRGBA64(color)
+		head := make([]byte, 12, 12+len(coordinates))
+		for i, v := range []uint16{offsetX, offsetY, c.R, c.G, c.B, c.A} {
+			binary.BigEndian.PutUint16(head[i*2:], v)
+		}
+		return append(head, coordinates...), nil
+	})
+}
+
+func (s *Stream) State() (*proto.State, error) {
+	return s.StateContext(context.Background())
+}
+
+func (s *Stream) StateContext(ctx context.Context) (*proto.State, error) {
+	resp, err := s.query(ctx, opState, nil)
+	if err != nil {
+		return nil, err
+	}
+	return decodeState(resp)
+}
+
+func (s *Stream) call(ctx context.Context, op uint8, payload func() ([]byte, error)) error {
+	_, err := s.query(ctx, op, payload)
+	return err
+}
+
+// query sends one request and waits for its response, payload is built in the stream order.
+// A request given up by ctx after being sent still runs on the server, its response is dropped.
+func (s *Stream) query(ctx context.Context, op uint8, payload func() ([]byte, error)) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	done := make(chan result, 1)
+
+	s.wl.Lock()
+	req := &frame{id: s.seq, code: op}
+	s.seq++
+
+	if payload != nil {
+		var err error
+		if req.payload, err = payload(); err != nil {
+			s.wl.Unlock()
+			return nil, err
+		}
+	}
+
+	s.pl.Lock()
+	if s.err != nil {
+		s.pl.Unlock()
+		s.wl.Unlock()
+		return nil, s.err
+	}
+	s.pending[req.id] = done
+	s.pl.Unlock()
+
+	// a frame written partially breaks the stream, so the deadline closes it
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = s.conn.SetWriteDeadline(deadline)
+	}
+	err := writeFrame(s.w, req)
+	if err == nil {
+		err = s.w.Flush()
+	}
+	_ = s.conn.SetWriteDeadline(time.Time{})
+	s.wl.Unlock()
+
+	if err != nil {
+		s.closing(err)
+	}
+
+	select {
+	case r := <-done:
+		return r.payload, r.err
+	case <-ctx.Done():
+		s.pl.Lock()
+		delete(s.pending, req.id)
+		s.pl.Unlock()
+		return nil, ctx.Err()
+	}
+}
+
+func (s *Stream) reading(r io.Reader) {
+	for {
+		resp, err := readFrame(r)
+		if err != nil {
+			s.closing(err)
+			return
+		}
+
+		s.pl.Lock()
+		done, ok := s.pending[resp.id]
+		delete(s.pending, resp.id)
+		s.pl.Unlock()
+
+		if !ok {
+			continue
+		}
+
+		if resp.code == 0 {
+			done <- result{payload: resp.payload}
+		} else {
+			done <- result{err: errors.New(string(resp.payload))}
+		}
+	}
+}
+
+// closing fails all waiting calls, the stream is unusable after that
+func (s *Stream) closing(err error) {
+	s.pl.Lock()
+	defer s.pl.Unlock()
+
+	if s.err == nil {
+		s.err = errors.Wrap(err, "stream closed")
+		_ = s.conn.Close()
+	}
+
+	for id, done := range s.pending {
+		done <- result{err: s.err}
+		delete(s.pending, id)
+	}
+}
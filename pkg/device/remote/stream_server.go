@@ -0,0 +1,193 @@
+package remote
+
+import (
+	"bufio"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"io"
+	"net/http"
+
+	"github.com/pkg/errors"
+
+	"usbscreen/pkg/bitmap"
+	"usbscreen/pkg/proto"
+)
+
+type streamHandler struct {
+	svc *Service
+}
+
+func (h *streamHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != "CONNECT" {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		_, _ = io.WriteString(w, "405 must CONNECT\n")
+		return
+	}
+
+	conn, rw, err := w.(http.Hijacker).Hijack()
+	if err != nil {
+		return
+	}
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	if _, err := io.WriteString(conn, "HTTP/1.0 "+streamConnected+"\n\n"); err != nil {
+		return
+	}
+
+	h.serve(rw.Reader, bufio.NewWriter(conn))
+}
+
+// session is the canvas of one connection, broken once a bitmap failed to decode
+type session struct {
+	shadow canvas
+	broken bool
+}
+
+func (h *streamHandler) serve(r *bufio.Reader, w *bufio.Writer) {
+	var sess session
+	for {
+		req, err := readFrame(r)
+		if err != nil {
+			return
+		}
+
+		resp := &frame{id: req.id}
+		if req.code == opState {
+			err = h.state(resp)
+		} else {
+			err = h.handle(req, &sess)
+		}
+		if err != nil {
+			resp.code = 1
+			resp.payload = []byte(err.Error())
+		}
+
+		if err := writeFrame(w, resp); err != nil {
+			return
+		}
+
+		// batch responses while pipelined requests are still buffered
+		if r.Buffered() == 0 {
+			if err := w.Flush(); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func (h *streamHandler) handle(req *frame, sess *session) error {
+	p := req.payload
+
+	switch req.code {
+	case opStartup:
+		return h.svc.Command("startup", nil)
+	case opShutdown:
+		return h.svc.Command("shutdown", nil)
+	case opRestart:
+		return h.svc.Command("restart", nil)
+	case opSetLight:
+		if len(p) != 1 {
+			return errors.New("invalid payload")
+		}
+		return h.svc.SetLight(p[0], nil)
+	case opSetMirror:
+		if len(p) != 1 {
+			return errors.New("invalid payload")
+		}
+		return h.svc.SetMirror(p[0] != 0, nil)
+	case opSetRotate:
+		if len(p) != 2 {
+			return errors.New("invalid payload")
+		}
+		return h.svc.SetRotate(SetRotateRequest{Landscape: p[0] != 0, Invert: p[1] != 0}, nil)
+	case opDrawBitmap:
+		var state proto.State
+		if err := h.svc.State(EmptyRequest{}, &state); err != nil {
+			sess.broken = true
+			return err
+		}
+		x, y, img, err := sess.decodeBitmap(p, image.Rect(0, 0, state.Width, state.Height))
+		if err != nil {
+			return err
+		}
+		return h.svc.drawBitmap(x, y, img)
+	case opDrawPixels:
+		if len(p) < 12 {
+			return errors.New("invalid payload")
+		}
+		var v [6]uint16
+		for i := range v {
+			v[i] = binary.BigEndian.Uint16(p[i*2:])
+		}
+		return h.svc.DrawPixels(&DrawPixelsRequest{
+			OffsetX:     v[0],
+			OffsetY:     v[1],
+			Color:       color.RGBA64{R: v[2], G: v[3], B: v[4], A: v[5]},
+			Coordinates: p[12:],
+		}, nil)
+	}
+
+	return errors.New("unknown operation")
+}
+
+func (h *streamHandler) state(resp *frame) error {
+	var state proto.State
+	if err := h.svc.State(EmptyRequest{}, &state); err != nil {
+		return err
+	}
+
+	resp.payload = encodeState(&state)
+	return nil
+}
+
+// decodeBitmap checks the region against screen before anything is allocated for it
+func (sess *session) decodeBitmap(p []byte, screen image.Rectangle) (uint16, uint16, image.Image, error) {
+	if len(p) < 9 {
+		sess.broken = true
+		return 0, 0, nil, errors.New("invalid payload")
+	}
+
+	x, y := binary.BigEndian.Uint16(p[0:2]), binary.BigEndian.Uint16(p[2:4])
+	w, h := int(binary.BigEndian.Uint16(p[4:6])), int(binary.BigEndian.Uint16(p[6:8]))
+	r := image.Rect(int(x), int(y), int(x)+w, int(y)+h)
+	if r.Empty() || !r.In(screen) {
+		sess.broken = true
+		return 0, 0, nil, errors.Errorf("bitmap %v out of screen %v", r, screen)
+	}
+
+	enc := p[8]
+	if enc&encodingReset != 0 {
+		sess.shadow.reset()
+		sess.broken = false
+		enc &^= encodingReset
+	}
+
+	data, size := p[9:], w*h*2
+	switch enc {
+	case EncodingRaw:
+		if len(data) != size {
+			sess.broken = true
+			return 0, 0, nil, errors.New("invalid bitmap size")
+		}
+	case EncodingDelta:
+		if sess.broken {
+			return 0, 0, nil, errors.New("canvas out of sync, waiting for a reset")
+		}
+		delta, err := inflate(data, size)
+		if err != nil {
+			sess.broken = true
+			return 0, 0, nil, errors.Wrap(err, "inflate failed")
+		}
+		data = sess.shadow.xor(int(x), int(y), w, h, delta)
+	default:
+		sess.broken = true
+		return 0, 0, nil, errors.New("unknown encoding")
+	}
+
+	sess.shadow.put(int(x), int(y), w, h, data)
+	return x, y, bitmap.WrapRGB565(image.Rect(0, 0, w, h), data), nil
+}
@@ -1,5 +1,12 @@
 package remote
 
+import (
+	"image/color"
+)
+
+type EmptyRequest struct {
+}
+
 type EmptyResponse struct {
 }
 
@@ -13,3 +20,10 @@ type DrawBitmapRequest struct {
 	PosY  uint16
 	Image []byte
 }
+
+type DrawPixelsRequest struct {
+	OffsetX     uint16
+	OffsetY     uint16
+	Color       color.RGBA64
+	Coordinates []uint8
+}
@@ -0,0 +1,68 @@
+package device
+
+import (
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+
+	"usbscreen/pkg/proto"
+)
+
+type Spec struct {
+	URL      *url.URL
+	Logger   *zap.Logger
+	Defaults url.Values
+}
+
+// Target is what the driver connects to, a port, an address or a directory
+func (s *Spec) Target() string {
+	if s.URL.Opaque != "" {
+		return s.URL.Opaque
+	}
+	return s.URL.Host + s.URL.Path
+}
+
+func (s *Spec) Get(key string) string {
+	if q := s.URL.Query(); q.Has(key) {
+		return q.Get(key)
+	}
+	return s.Defaults.Get(key)
+}
+
+func (s *Spec) Values(key string) []string {
+	return s.URL.Query()[key]
+}
+
+func (s *Spec) Int(key string, def int) (int, error) {
+	v := s.Get(key)
+	if v == "" {
+		return def, nil
+	}
+
+	i, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, errors.Wrapf(err, "invalid %s", key)
+	}
+	return i, nil
+}
+
+func (s *Spec) Duration(key string, def time.Duration) (time.Duration, error) {
+	v := s.Get(key)
+	if v == "" {
+		return def, nil
+	}
+
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return 0, errors.Wrapf(err, "invalid %s", key)
+	}
+	return d, nil
+}
+
+// Open opens a nested device with the same logger and defaults
+func (s *Spec) Open(spec string) (proto.Control, error) {
+	return Open(spec, s.Logger, s.Defaults)
+}
@@ -0,0 +1,34 @@
+package virtual
+
+import (
+	"usbscreen/pkg/device"
+	"usbscreen/pkg/proto"
+)
+
+func init() {
+	device.Register(&device.Driver{
+		Name:   "mock",
+		New:    openMock,
+		Width:  320,
+		Height: 480,
+	})
+	device.Register(&device.Driver{
+		Name:   "screen",
+		New:    openScreen,
+		Width:  320,
+		Height: 480,
+	})
+}
+
+func openMock(spec *device.Spec) (proto.Control, error) {
+	return Mock(spec.Logger), nil
+}
+
+// openScreen accepts screen:///path/to/dump, frames are only kept in memory without the path
+func openScreen(spec *device.Spec) (proto.Control, error) {
+	var opts []Option
+	if dir := spec.Target(); dir != "" {
+		opts = append(opts, WithDump(dir))
+	}
+	return NewScreen(spec.Logger, opts...), nil
+}
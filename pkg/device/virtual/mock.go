@@ -1,7 +1,9 @@
 package virtual
 
 import (
+	"context"
 	"image"
+	"image/color"
 
 	"go.uber.org/zap"
 
@@ -9,20 +11,30 @@ import (
 )
 
 func Mock(logger *zap.Logger) proto.Control {
-	return &Mocker{logger}
+	return &Mocker{
+		l: logger,
+		state: proto.State{
+			Powered: true,
+			Width:   320,
+			Height:  480,
+		},
+	}
 }
 
 type Mocker struct {
-	l *zap.Logger
+	l     *zap.Logger
+	state proto.State
 }
 
 func (m *Mocker) Startup() error {
 	m.l.Info("startup")
+	m.state.Powered = true
 	return nil
 }
 
 func (m *Mocker) Shutdown() error {
 	m.l.Info("shutdown")
+	m.state.Powered = false
 	return nil
 }
 
@@ -33,16 +45,22 @@ func (m *Mocker) Restart() error {
 
 func (m *Mocker) SetLight(light uint8) error {
 	m.l.With(zap.Uint8("light", light)).Info("set-light")
+	m.state.Light = &light
 	return nil
 }
 
 func (m *Mocker) SetMirror(mirror bool) error {
 	m.l.With(zap.Bool("mirror", mirror)).Info("set-mirror")
+	m.state.Mirror = mirror
 	return nil
 }
 
 func (m *Mocker) SetRotate(landscape bool, invert bool) error {
 	m.l.With(zap.Bool("landscape", landscape), zap.Bool("invert", invert)).Info("set-rotate")
+	if landscape != m.state.Landscape {
+		m.state.Width, m.state.Height = m.state.Height, m.state.Width
+	}
+	m.state.Landscape, m.state.Invert = landscape, invert
 	return nil
 }
 
@@ -55,3 +73,88 @@ func (m *Mocker) DrawBitmap(posX uint16, posY uint16, image image.Image) error {
 	).Info("draw-bitmap")
 	return nil
 }
+
+func (m *Mocker) DrawPixels(offsetX uint16, offsetY uint16, color color.Color, coordinates []uint8) error {
+	m.l.With(
+		zap.Uint16("x", offsetX),
+		zap.Uint16("y", offsetY),
+		zap.Any("color", color),
+		zap.Int("pixels", len(coordinates)/2),
+	).Info("draw-pixels")
+	return nil
+}
+
+func (m *Mocker) State() (*proto.State, error) {
+	state := m.state
+	return &state, nil
+}
+
+// StartupContext and the other context calls never block, ctx is only checked before running
+func (m *Mocker) StartupContext(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return m.Startup()
+}
+
+func (m *Mocker) ShutdownContext(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return m.Shutdown()
+}
+
+func (m *Mocker) RestartContext(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return m.Restart()
+}
+
+func (m *Mocker) SetLightContext(ctx context.Context, light uint8) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return m.SetLight(light)
+}
+
+func (m *Mocker) SetMirrorContext(ctx context.Context, mirror bool) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return m.SetMirror(mirror)
+}
+
+func (m *Mocker) SetRotateContext(ctx context.Context, landscape bool, invert bool) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return m.SetRotate(landscape, invert)
+}
+
+func (m *Mocker) DrawPixelsContext(ctx context.Context, offsetX uint16, offsetY uint16, color color.Color, coordinates []uint8) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return m.DrawPixels(offsetX, offsetY, color, coordinates)
+}
+
+func (m *Mocker) DrawBitmapContext(ctx context.Context, posX uint16, posY uint16, image image.Image) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := m.DrawBitmap(posX, posY, image); err != nil {
+		return err
+	}
+
+	total := image.Bounds().Dx() * image.Bounds().Dy() * 2
+	proto.ProgressFrom(ctx)(total, total)
+	return nil
+}
+
+func (m *Mocker) StateContext(ctx context.Context) (*proto.State, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return m.State()
+}
@@ -0,0 +1,366 @@
+package virtual
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+
+	"usbscreen/pkg/bitmap"
+	"usbscreen/pkg/proto"
+)
+
+type Option func(s *Screen)
+
+// WithDump saves the frame into dir after every draw
+func WithDump(dir string) Option {
+	return func(s *Screen) {
+		s.dump = dir
+	}
+}
+
+// WithSize overrides the native (portrait) resolution of the panel
+func WithSize(width, height int) Option {
+	return func(s *Screen) {
+		s.width, s.height = width, height
+	}
+}
+
+// NewScreen creates a virtual panel which keeps what would be shown in memory,
+// so the frame could be inspected or saved as PNG without the hardware.
+func NewScreen(logger *zap.Logger, opts ...Option) *Screen {
+	s := &Screen{
+		l:       logger,
+		width:   320,
+		height:  480,
+		powered: true,
+	}
+
+	if logger == nil {
+		s.l = zap.NewNop()
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	s.fb = bitmap.NewRGB565(image.Rect(0, 0, s.width, s.height))
+	return s
+}
+
+type Screen struct {
+	sync.Mutex
+	l  *zap.Logger
+	fb *bitmap.RGB565
+	// native portrait size
+	width  int
+	height int
+	// panel states
+	powered   bool
+	light     uint8
+	mirror    bool
+	landscape bool
+	invert    bool
+	// dumping
+	dump   string
+	frames int
+}
+
+func (s *Screen) Startup() error {
+	s.Lock()
+	defer s.Unlock()
+
+	s.powered = true
+	return s.dumping()
+}
+
+func (s *Screen) Shutdown() error {
+	s.Lock()
+	defer s.Unlock()
+
+	s.powered = false
+	return s.dumping()
+}
+
+func (s *Screen) Restart() error {
+	s.Lock()
+	defer s.Unlock()
+
+	s.fb = bitmap.NewRGB565(image.Rect(0, 0, s.width, s.height))
+	s.powered = true
+	s.light, s.mirror, s.landscape, s.invert = 0, false, false, false
+	return s.dumping()
+}
+
+func (s *Screen) SetLight(light uint8) error {
+	s.Lock()
+	defer s.Unlock()
+
+	s.light = light
+	return s.dumping()
+}
+
+func (s *Screen) SetMirror(mirror bool) error {
+	s.Lock()
+	defer s.Unlock()
+
+	s.mirror = mirror
+	return s.dumping()
+}
+
+func (s *Screen) SetRotate(landscape bool, invert bool) error {
+	s.Lock()
+	defer s.Unlock()
+
+	s.landscape, s.invert = landscape, invert
+	return s.dumping()
+}
+
+func (s *Screen) DrawBitmap(posX uint16, posY uint16, image image.Image) error {
+	s.Lock()
+	defer s.Unlock()
+
+	rect := image.Bounds()
+	imgW, imgH := rect.Dx(), rect.Dy()
+
+	lw, lh := s.logical()
+	if imgW+int(posX) > lw {
+		return errors.New("width overflow")
+	} else if imgH+int(posY) > lh {
+		return errors.New("height overflow")
+	}
+
+	// encode as the real device does, transparent pixels are sent as black
+	bmp := bitmap.Encode(image)
+	for y := 0; y < imgH; y++ {
+		for x := 0; x < imgW; x++ {
+			i := (y*imgW + x) * 2
+			px, py := s.physical(int(posX)+x, int(posY)+y, s.mirror)
+			s.fb.SetRGB565(px, py, uint16(bmp[i+1])<<8|uint16(bmp[i]))
+		}
+	}
+
+	s.l.With(
+		zap.Uint16("x", posX),
+		zap.Uint16("y", posY),
+		zap.Int("w", imgW),
+		zap.Int("h", imgH),
+	).Debug("draw-bitmap")
+
+	return s.dumping()
+}
+
+func (s *Screen) DrawPixels(offsetX uint16, offsetY uint16, color color.Color, coordinates []uint8) error {
+	s.Lock()
+	defer s.Unlock()
+
+	if len(coordinates)%2 != 0 {
+		return errors.New("coordinates not paired")
+	}
+
+	lw, lh := s.logical()
+	rgb := bitmap.ToRGB565(color)
+	for n := 0; n < len(coordinates); n += 2 {
+		x, y := int(offsetX)+int(coordinates[n]), int(offsetY)+int(coordinates[n+1])
+		if x >= lw {
+			return errors.New("width overflow")
+		} else if y >= lh {
+			return errors.New("height overflow")
+		}
+		px, py := s.physical(x, y, s.mirror)
+		s.fb.SetRGB565(px, py, rgb)
+	}
+
+	return s.dumping()
+}
+
+func (s *Screen) State() (*proto.State, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	lw, lh := s.logical()
+	light := s.light
+	return &proto.State{
+		Powered:   s.powered,
+		Light:     &light,
+		Mirror:    s.mirror,
+		Landscape: s.landscape,
+		Invert:    s.invert,
+		Width:     lw,
+		Height:    lh,
+	}, nil
+}
+
+// StartupContext and the other context calls never block, ctx is only checked before running
+func (s *Screen) StartupContext(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return s.Startup()
+}
+
+func (s *Screen) ShutdownContext(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return s.Shutdown()
+}
+
+func (s *Screen) RestartContext(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return s.Restart()
+}
+
+func (s *Screen) SetLightContext(ctx context.Context, light uint8) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return s.SetLight(light)
+}
+
+func (s *Screen) SetMirrorContext(ctx context.Context, mirror bool) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return s.SetMirror(mirror)
+}
+
+func (s *Screen) SetRotateContext(ctx context.Context, landscape bool, invert bool) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return s.SetRotate(landscape, invert)
+}
+
+func (s *Screen) DrawPixelsContext(ctx context.Context, offsetX uint16, offsetY uint16, color color.Color, coordinates []uint8) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return s.DrawPixels(offsetX, offsetY, color, coordinates)
+}
+
+func (s *Screen) DrawBitmapContext(ctx context.Context, posX uint16, posY uint16, image image.Image) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := s.DrawBitmap(posX, posY, image); err != nil {
+		return err
+	}
+
+	total := image.Bounds().Dx() * image.Bounds().Dy() * 2
+	proto.ProgressFrom(ctx)(total, total)
+	return nil
+}
+
+func (s *Screen) StateContext(ctx context.Context) (*proto.State, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return s.State()
+}
+
+// Frame returns what the panel is showing, viewed in the current orientation
+// with the brightness applied.
+func (s *Screen) Frame() image.Image {
+	s.Lock()
+	defer s.Unlock()
+
+	return s.frame()
+}
+
+// SavePNG writes the current frame to file
+func (s *Screen) SavePNG(file string) error {
+	s.Lock()
+	defer s.Unlock()
+
+	return s.save(file)
+}
+
+func (s *Screen) logical() (int, int) {
+	if s.landscape {
+		return s.height, s.width
+	}
+	return s.width, s.height
+}
+
+// physical maps a logical coordinate to the panel memory
+func (s *Screen) physical(x, y int, mirror bool) (int, int) {
+	lw, _ := s.logical()
+	if mirror {
+		x = lw - 1 - x
+	}
+
+	switch {
+	case s.landscape && s.invert:
+		return y, s.height - 1 - x
+	case s.landscape:
+		return s.width - 1 - y, x
+	case s.invert:
+		return s.width - 1 - x, s.height - 1 - y
+	}
+	return x, y
+}
+
+func (s *Screen) frame() *image.RGBA {
+	lw, lh := s.logical()
+	img := image.NewRGBA(image.Rect(0, 0, lw, lh))
+	if !s.powered {
+		draw.Draw(img, img.Bounds(), image.Black, image.Point{}, draw.Src)
+		return img
+	}
+
+	// light 0 is the brightest level
+	level := 255 - uint32(s.light)
+	for y := 0; y < lh; y++ {
+		for x := 0; x < lw; x++ {
+			// the mirror only changes how pixels are written, the glass shows them as is
+			r, g, b, _ := s.fb.At(s.physical(x, y, false)).RGBA()
+			img.SetRGBA(x, y, color.RGBA{
+				R: uint8((r >> 8) * level / 255),
+				G: uint8((g >> 8) * level / 255),
+				B: uint8((b >> 8) * level / 255),
+				A: 0xFF,
+			})
+		}
+	}
+	return img
+}
+
+func (s *Screen) save(file string) error {
+	f, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+
+	if err := png.Encode(f, s.frame()); err != nil {
+		_ = f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+func (s *Screen) dumping() error {
+	if s.dump == "" {
+		return nil
+	}
+
+	s.frames++
+	file := filepath.Join(s.dump, fmt.Sprintf("frame-%05d.png", s.frames))
+	if err := s.save(file); err != nil {
+		return errors.Wrap(err, "dump frame failed")
+	}
+
+	s.l.With(zap.String("file", file)).Debug("dumped")
+	return nil
+}
@@ -0,0 +1,129 @@
+package mixer
+
+import (
+	"bytes"
+	"image"
+	"image/draw"
+)
+
+// snapshot copies img into a new RGBA with bounds starting at 0,0
+func snapshot(img image.Image) *image.RGBA {
+	b := img.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(dst, dst.Bounds(), img, b.Min, draw.Src)
+	return dst
+}
+
+// clone copies img keeping its bounds
+func clone(img *image.RGBA) *image.RGBA {
+	dst := *img
+	dst.Pix = append([]uint8(nil), img.Pix...)
+	return &dst
+}
+
+// black returns an opaque black frame of size
+func black(size image.Point) *image.RGBA {
+	img := image.NewRGBA(image.Rectangle{Max: size})
+	for i := 3; i < len(img.Pix); i += 4 {
+		img.Pix[i] = 0xFF
+	}
+	return img
+}
+
+// compose copies base and blends img over it with the top left at the position
+func compose(base *image.RGBA, img image.Image, at image.Point) *image.RGBA {
+	dst := clone(base)
+	b := img.Bounds()
+	draw.Draw(dst, b.Sub(b.Min).Add(at), img, b.Min, draw.Over)
+	return dst
+}
+
+// diffRects splits both frames into tiles and returns the changed regions,
+// adjacent dirty tiles are merged into bigger rectangles.
+func diffRects(prev, next *image.RGBA, tile int) []image.Rectangle {
+	b := next.Bounds()
+	cols := (b.Dx() + tile - 1) / tile
+	rows := (b.Dy() + tile - 1) / tile
+
+	var rects []image.Rectangle
+	// rectangles reaching the previous tile row, keyed by their x span
+	opened := make(map[[2]int]int)
+
+	for row := 0; row < rows; row++ {
+		next2 := make(map[[2]int]int)
+		for col := 0; col < cols; {
+			if !tileChanged(prev, next, tileRect(b, col, row, tile)) {
+				col++
+				continue
+			}
+
+			start := col
+			for col < cols && tileChanged(prev, next, tileRect(b, col, row, tile)) {
+				col++
+			}
+
+			r := tileRect(b, start, row, tile).Union(tileRect(b, col-1, row, tile))
+			span := [2]int{r.Min.X, r.Max.X}
+			if i, ok := opened[span]; ok {
+				rects[i].Max.Y = r.Max.Y
+				next2[span] = i
+			} else {
+				rects = append(rects, r)
+				next2[span] = len(rects) - 1
+			}
+		}
+		opened = next2
+	}
+
+	return rects
+}
+
+func tileRect(b image.Rectangle, col, row, tile int) image.Rectangle {
+	return image.Rect(col*tile, row*tile, (col+1)*tile, (row+1)*tile).Intersect(b)
+}
+
+func tileChanged(prev, next *image.RGBA, r image.Rectangle) bool {
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		i := prev.PixOffset(r.Min.X, y)
+		j := i + r.Dx()*4
+		if !bytes.Equal(prev.Pix[i:j], next.Pix[i:j]) {
+			return true
+		}
+	}
+	return false
+}
+
+// tileDistance sums the channel differences in r, which is how much a region changed
+func tileDistance(prev, next *image.RGBA, r image.Rectangle) int {
+	var d int
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		i := prev.PixOffset(r.Min.X, y)
+		for j := i; j < i+r.Dx()*4; j++ {
+			if v := int(prev.Pix[j]) - int(next.Pix[j]); v < 0 {
+				d -= v
+			} else {
+				d += v
+			}
+		}
+	}
+	return d
+}
+
+// asRGBA returns img as RGBA starting at 0,0, it is nil if img is nil
+func asRGBA(img image.Image) *image.RGBA {
+	if img == nil {
+		return nil
+	}
+	if rgba, ok := img.(*image.RGBA); ok && rgba.Rect.Min == (image.Point{}) {
+		return rgba
+	}
+	return snapshot(img)
+}
+
+func area(rects []image.Rectangle) int {
+	var n int
+	for _, r := range rects {
+		n += r.Dx() * r.Dy()
+	}
+	return n
+}
@@ -1,45 +1,258 @@
 package mixer
 
 import (
+	"context"
 	"image"
+	"image/draw"
+	"sync"
+	"sync/atomic"
 
 	"github.com/samber/lo"
 
+	"usbscreen/pkg/bitmap"
 	"usbscreen/pkg/proto"
 )
 
 func NewDrawer(dst proto.Control, opts ...Option) *Drawer {
 	d := &Drawer{
-		dev: dst,
+		dev:  proto.WithContext(dst),
+		tile: 16,
+	}
+	if r, ok := dst.(proto.FormatReporter); ok {
+		d.format = r.Format()
+	}
+	// the profile goes before the dithering, so the device must not apply it again
+	if c, ok := dst.(proto.Calibrator); ok {
+		d.profile, d.dev = c.Calibration()
 	}
 
 	for _, opt := range opts {
 		opt(d)
 	}
 
+	if n, ok := dst.(proto.ResetNotifier); ok {
+		n.OnReset(func() {
+			atomic.StoreInt32(&d.lost, 1)
+		})
+	}
+
 	return d
 }
 
 type Drawer struct {
-	dev  proto.Control
+	l   sync.Mutex
+	dev proto.ContextControl
+	// el only guards effs, so they could be replaced during a transition
+	el   sync.Mutex
 	effs []Effect
+	tile int
+	// last is the composed content, sent is what the screen shows after dithering
+	last *image.RGBA
+	sent *image.RGBA
+	// lost is set once the device dropped its content, the next draw is then full
+	lost int32
+	// options
+	dither  bitmap.DitherMode
+	format  bitmap.Format
+	profile *bitmap.Profile
 }
 
 func (d *Drawer) Canvas(img image.Image) error {
-	eff := lo.Sample(d.effs)
+	return d.CanvasContext(context.Background(), img)
+}
+
+// CanvasContext draws img blended over the last frame, the progress attached to ctx
+// is reported for the whole canvas
+func (d *Drawer) CanvasContext(ctx context.Context, img image.Image) error {
+	d.l.Lock()
+	defer d.l.Unlock()
+
+	var frame *image.RGBA
+	if o, ok := img.(interface{ Opaque() bool }); ok && o.Opaque() {
+		frame = snapshot(img)
+	} else {
+		size := img.Bounds().Size()
+		frame = compose(d.base(size), img, image.Point{})
+	}
+
+	return d.render(ctx, frame, d.pick())
+}
+
+// SetEffects replaces the effects, one of them is picked randomly for every canvas
+func (d *Drawer) SetEffects(e ...Effect) {
+	d.el.Lock()
+	defer d.el.Unlock()
+
+	d.effs = e
+}
+
+func (d *Drawer) Effects() []Effect {
+	d.el.Lock()
+	defer d.el.Unlock()
+
+	return append([]Effect(nil), d.effs...)
+}
+
+func (d *Drawer) pick() Effect {
+	d.el.Lock()
+	defer d.el.Unlock()
+
+	return lo.Sample(d.effs)
+}
+
+func (d *Drawer) Overlay(img image.Image, at image.Point) error {
+	return d.OverlayContext(context.Background(), img, at)
+}
+
+// OverlayContext blends img at the position over the last frame and draws the changed part,
+// the screen is taken as black if nothing was drawn yet or it was rotated since
+func (d *Drawer) OverlayContext(ctx context.Context, img image.Image, at image.Point) error {
+	d.l.Lock()
+	defer d.l.Unlock()
+
+	state, err := d.dev.StateContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	return d.render(ctx, compose(d.base(image.Pt(state.Width, state.Height)), img, at), nil)
+}
+
+// base returns the last frame if it is in size, a black one otherwise
+func (d *Drawer) base(size image.Point) *image.RGBA {
+	if d.last != nil && d.last.Bounds().Size() == size {
+		return d.last
+	}
+	return black(size)
+}
+
+// draw renders a frame composed by the caller, without effects
+func (d *Drawer) draw(ctx context.Context, frame *image.RGBA) error {
+	d.l.Lock()
+	defer d.l.Unlock()
+
+	return d.render(ctx, frame, nil)
+}
+
+func (d *Drawer) render(ctx context.Context, frame *image.RGBA, eff Effect) error {
+	sent := d.reduce(frame)
+
+	// screen content is unknown once a draw failed halfway
+	prev := d.sent
+	d.last, d.sent = frame, nil
+	if atomic.SwapInt32(&d.lost, 0) == 1 {
+		prev = nil
+	}
+
+	var err error
 	if eff != nil {
-		w, err := eff.Process(img.(Image))
-		if err != nil {
+		err = d.drawEffect(ctx, eff, prev, sent)
+	} else {
+		err = d.drawChanged(ctx, prev, sent)
+	}
+	if err != nil {
+		return err
+	}
+
+	// the parts drawn before a reconnect are gone, so it is drawn again in full
+	if atomic.SwapInt32(&d.lost, 0) == 1 {
+		if err := d.dev.DrawBitmapContext(ctx, 0, 0, sent); err != nil {
 			return err
 		}
+	}
+
+	d.sent = sent
+	return nil
+}
+
+// reduce applies the profile and the dithering to frame. Floyd-Steinberg carries the
+// error through the whole frame, so a small change would alter most of the screen:
+// when the last frame was sent, only the changed tiles are reduced into a copy of
+// what was sent, with Bayer as there is no error to carry over from the rest.
+func (d *Drawer) reduce(frame *image.RGBA) *image.RGBA {
+	if d.profile == nil && d.dither == bitmap.DitherNone {
+		return frame
+	}
+
+	if d.dither != bitmap.DitherFloydSteinberg || d.tile <= 0 || d.last == nil || d.sent == nil ||
+		d.last.Bounds() != frame.Bounds() || d.sent.Bounds() != frame.Bounds() {
+		sent := clone(frame)
+		if d.profile != nil {
+			d.profile.Apply(sent)
+		}
+		bitmap.DitherFormat(sent, d.dither, d.format)
+		return sent
+	}
+
+	sent := clone(d.sent)
+	for _, r := range diffRects(d.last, frame, d.tile) {
+		part := sent.SubImage(r).(*image.RGBA)
+		draw.Draw(part, r, frame, r.Min, draw.Src)
+		if d.profile != nil {
+			d.profile.Apply(part)
+		}
+		bitmap.DitherFormat(part, bitmap.DitherBayer, d.format)
+	}
+	return sent
+}
+
+func (d *Drawer) drawEffect(ctx context.Context, eff Effect, prev, next *image.RGBA) error {
+	if prev != nil && prev.Bounds() != next.Bounds() {
+		prev = nil
+	}
+
+	// a typed nil must not reach the effect as a frame
+	var from Image
+	if prev != nil {
+		from = prev
+	}
+
+	w, err := eff.Process(from, next)
+	if err != nil {
+		return err
+	}
+
+	if t, ok := eff.(timedEffect); ok && t.Duration() > 0 {
+		return d.drawPaced(ctx, w, prev, next, t.Duration())
+	}
 
-		for w2 := range w {
-			if err := d.dev.DrawBitmap(uint16(w2.At.X), uint16(w2.At.Y), w2.Img); err != nil {
-				return err
+	for w2 := range w {
+		if err := d.dev.DrawBitmapContext(ctx, uint16(w2.At.X), uint16(w2.At.Y), w2.Img); err != nil {
+			// let the effect finish its goroutine
+			for range w {
 			}
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (d *Drawer) drawChanged(ctx context.Context, prev, frame *image.RGBA) error {
+	if d.tile <= 0 || prev == nil || prev.Bounds() != frame.Bounds() {
+		return d.dev.DrawBitmapContext(ctx, 0, 0, frame)
+	}
+
+	rects := diffRects(prev, frame, d.tile)
+
+	// a single transfer is cheaper than lots of commands covering most of the screen
+	if area(rects)*4 >= frame.Bounds().Dx()*frame.Bounds().Dy()*3 {
+		return d.dev.DrawBitmapContext(ctx, 0, 0, frame)
+	}
+
+	progress := proto.ProgressFrom(ctx)
+	total := area(rects) * d.format.Size()
+
+	var done int
+	for _, r := range rects {
+		ctx2 := proto.WithProgress(ctx, func(sent, _ int) {
+			progress(done+sent, total)
+		})
+		if err := d.dev.DrawBitmapContext(ctx2, uint16(r.Min.X), uint16(r.Min.Y), frame.SubImage(r)); err != nil {
+			return err
 		}
-		return nil
+		done += r.Dx() * r.Dy() * d.format.Size()
 	}
 
-	return d.dev.DrawBitmap(0, 0, img)
+	return nil
 }
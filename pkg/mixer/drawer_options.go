@@ -1,5 +1,9 @@
 package mixer
 
+import (
+	"usbscreen/pkg/bitmap"
+)
+
 type Option func(d *Drawer)
 
 func WithEffect(e ...Effect) Option {
@@ -7,3 +11,33 @@ func WithEffect(e ...Effect) Option {
 		d.effs = e
 	}
 }
+
+// WithDiffTile sets the tile size used for finding changed regions between frames, 0 to always draw full
+func WithDiffTile(size int) Option {
+	return func(d *Drawer) {
+		d.tile = size
+	}
+}
+
+// WithDither reduces the frames to the panel format with mode before they are compared and sent.
+// Floyd-Steinberg is used for full frames, the changed regions of partial updates use Bayer.
+func WithDither(mode bitmap.DitherMode) Option {
+	return func(d *Drawer) {
+		d.dither = mode
+	}
+}
+
+// WithProfile calibrates the frames before they are dithered, taken from the device
+// if it is calibrated
+func WithProfile(p *bitmap.Profile) Option {
+	return func(d *Drawer) {
+		d.profile = p
+	}
+}
+
+// WithFormat sets the panel format dithered to, taken from the device if it reports one
+func WithFormat(f bitmap.Format) Option {
+	return func(d *Drawer) {
+		d.format = f
+	}
+}
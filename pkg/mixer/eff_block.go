@@ -8,15 +8,17 @@ import (
 	"github.com/samber/lo"
 )
 
-func EffectBlock() Effect {
+// EffectBlock draws blocks in random order, the block size is random unless set
+func EffectBlock(opts ...EffectOption) Effect {
+	c := newEffectConfig(0, opts)
 	return &block{
-		size: 32,
-		rand: true,
+		effectConfig: c,
+		rand:         !c.sized,
 	}
 }
 
 type block struct {
-	size int
+	effectConfig
 	rand bool
 }
 
@@ -24,39 +26,22 @@ func (e *block) Name() string {
 	return "block"
 }
 
-func (e *block) Process(img Image) (<-chan Write, error) {
-	wc := make(chan Write)
+func (e *block) Process(prev, next Image) (<-chan Write, error) {
+	r := next.Bounds()
 
-	go func() {
-		r := img.Bounds()
-		w, h := r.Dx(), r.Dy()
-
-		size := e.size
-		if e.rand {
-			rand.Seed(time.Now().UnixNano())
-			size = rand.Intn(32) + 8
-		}
-
-		var ws []Write
-		for x := 0; x < w; x += size {
-			for y := 0; y < h; y += size {
-				ws = append(ws, Write{
-					At:  image.Pt(x, y),
-					Img: img.SubImage(image.Rect(x, y, x+size, y+size)),
-				})
-			}
-		}
-
-		if e.rand {
-			lo.Shuffle(ws)
-		}
+	size := e.size
+	if e.rand {
+		rand.Seed(time.Now().UnixNano())
+		size = rand.Intn(32) + 8
+	}
 
-		for _, w2 := range ws {
-			wc <- w2
+	var rs []image.Rectangle
+	for x := r.Min.X; x < r.Max.X; x += size {
+		for y := r.Min.Y; y < r.Max.Y; y += size {
+			rs = append(rs, image.Rect(x, y, x+size, y+size))
 		}
+	}
 
-		close(wc)
-	}()
-
-	return wc, nil
+	lo.Shuffle(rs)
+	return emit(prev, next, rs, e.effectConfig), nil
 }
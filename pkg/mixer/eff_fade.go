@@ -0,0 +1,63 @@
+package mixer
+
+import "image"
+
+// EffectCrossfade draws frames blended from the previous image, steps sets how many,
+// only the changed regions are blended
+func EffectCrossfade(opts ...EffectOption) Effect {
+	return &crossfade{effectConfig: newEffectConfig(0, opts)}
+}
+
+type crossfade struct {
+	effectConfig
+}
+
+func (e *crossfade) Name() string {
+	return "crossfade"
+}
+
+func (e *crossfade) Process(prev, next Image) (<-chan Write, error) {
+	wc := make(chan Write)
+
+	go func() {
+		defer close(wc)
+
+		b := next.Bounds()
+		if prev == nil || prev.Bounds().Size() != b.Size() {
+			wc <- Write{Img: next}
+			return
+		}
+
+		from, to := asRGBA(prev), asRGBA(next)
+
+		rects := diffRects(from, to, 16)
+		if len(rects) == 0 {
+			return
+		}
+
+		for i := 1; i < e.steps; i++ {
+			for _, r := range rects {
+				wc <- Write{At: r.Min, Img: blend(from, to, r, i*256/e.steps)}
+			}
+			e.pause()
+		}
+
+		for _, r := range rects {
+			wc <- Write{At: r.Min, Img: to.SubImage(r)}
+		}
+	}()
+
+	return wc, nil
+}
+
+// blend mixes r of the frames with weight of to in 0-256
+func blend(from, to *image.RGBA, r image.Rectangle, weight int) *image.RGBA {
+	dst := image.NewRGBA(r)
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		i, j := from.PixOffset(r.Min.X, y), dst.PixOffset(r.Min.X, y)
+		for k := 0; k < r.Dx()*4; k++ {
+			dst.Pix[j+k] = uint8((int(from.Pix[i+k])*(256-weight) + int(to.Pix[i+k])*weight) >> 8)
+		}
+	}
+	return dst
+}
@@ -0,0 +1,94 @@
+package mixer
+
+import (
+	"image"
+)
+
+// EffectSpiral draws blocks clockwise from the edges to the center, or outwards
+// when the direction is left or up
+func EffectSpiral(opts ...EffectOption) Effect {
+	return &spiral{effectConfig: newEffectConfig(32, opts)}
+}
+
+type spiral struct {
+	effectConfig
+}
+
+func (e *spiral) Name() string {
+	return "spiral"
+}
+
+func (e *spiral) Process(prev, next Image) (<-chan Write, error) {
+	b := next.Bounds()
+	cols := (b.Dx() + e.size - 1) / e.size
+	rows := (b.Dy() + e.size - 1) / e.size
+
+	cell := func(col, row int) image.Rectangle {
+		p := b.Min.Add(image.Pt(col*e.size, row*e.size))
+		return image.Rectangle{Min: p, Max: p.Add(image.Pt(e.size, e.size))}
+	}
+
+	var rs []image.Rectangle
+	left, top, right, bottom := 0, 0, cols-1, rows-1
+	for left <= right && top <= bottom {
+		for c := left; c <= right; c++ {
+			rs = append(rs, cell(c, top))
+		}
+		for r := top + 1; r <= bottom; r++ {
+			rs = append(rs, cell(right, r))
+		}
+		if top < bottom {
+			for c := right - 1; c >= left; c-- {
+				rs = append(rs, cell(c, bottom))
+			}
+		}
+		if left < right {
+			for r := bottom - 1; r > top; r-- {
+				rs = append(rs, cell(left, r))
+			}
+		}
+		left, top, right, bottom = left+1, top+1, right-1, bottom-1
+	}
+
+	if e.dir.reverse() {
+		reverse(rs)
+	}
+	return emit(prev, next, rs, e.effectConfig), nil
+}
+
+// EffectChecker fades in a checkerboard of blocks in 4 passes of an ordered pattern
+func EffectChecker(opts ...EffectOption) Effect {
+	return &checker{effectConfig: newEffectConfig(32, opts)}
+}
+
+type checker struct {
+	effectConfig
+}
+
+func (e *checker) Name() string {
+	return "checker"
+}
+
+var checkerPasses = [2][2]int{{0, 2}, {3, 1}}
+
+func (e *checker) Process(prev, next Image) (<-chan Write, error) {
+	b := next.Bounds()
+
+	var rs []image.Rectangle
+	for pass := 0; pass < 4; pass++ {
+		for y := 0; y*e.size < b.Dy(); y++ {
+			for x := 0; x*e.size < b.Dx(); x++ {
+				if checkerPasses[y&1][x&1] != pass {
+					continue
+				}
+				p := b.Min.Add(image.Pt(x*e.size, y*e.size))
+				rs = append(rs, image.Rectangle{Min: p, Max: p.Add(image.Pt(e.size, e.size))})
+			}
+		}
+	}
+
+	if e.dir.reverse() {
+		reverse(rs)
+	}
+	return emit(prev, next, rs, e.effectConfig), nil
+}
@@ -0,0 +1,92 @@
+package mixer
+
+import (
+	"image"
+)
+
+// EffectWipe reveals the image in strips moving along the direction
+func EffectWipe(opts ...EffectOption) Effect {
+	return &wipe{effectConfig: newEffectConfig(16, opts)}
+}
+
+type wipe struct {
+	effectConfig
+}
+
+func (e *wipe) Name() string {
+	return "wipe"
+}
+
+func (e *wipe) Process(prev, next Image) (<-chan Write, error) {
+	return emit(prev, next, strips(next.Bounds(), e.size, e.dir), e.effectConfig), nil
+}
+
+// EffectBlinds opens slats of size at once, horizontal slats unless the direction is left or right
+func EffectBlinds(opts ...EffectOption) Effect {
+	return &blinds{effectConfig: newEffectConfig(40, opts)}
+}
+
+type blinds struct {
+	effectConfig
+}
+
+func (e *blinds) Name() string {
+	return "blinds"
+}
+
+func (e *blinds) Process(prev, next Image) (<-chan Write, error) {
+	slats := strips(next.Bounds(), e.size, e.dir)
+
+	// every step draws one band in all slats
+	band := e.size / 8
+	if band < 1 {
+		band = 1
+	}
+
+	var rs []image.Rectangle
+	for i := 0; i < e.size; i += band {
+		for _, s := range slats {
+			if e.dir.horizontal() {
+				x := s.Min.X + i
+				if e.dir.reverse() {
+					x = s.Max.X - i - band
+				}
+				rs = append(rs, image.Rect(x, s.Min.Y, x+band, s.Max.Y).Intersect(s))
+			} else {
+				y := s.Min.Y + i
+				if e.dir.reverse() {
+					y = s.Max.Y - i - band
+				}
+				rs = append(rs, image.Rect(s.Min.X, y, s.Max.X, y+band).Intersect(s))
+			}
+		}
+	}
+
+	return emit(prev, next, rs, e.effectConfig), nil
+}
+
+// EffectScanline draws the odd lines first and then the even ones, lines are size thick
+func EffectScanline(opts ...EffectOption) Effect {
+	return &scanline{effectConfig: newEffectConfig(4, opts)}
+}
+
+type scanline struct {
+	effectConfig
+}
+
+func (e *scanline) Name() string {
+	return "scanline"
+}
+
+func (e *scanline) Process(prev, next Image) (<-chan Write, error) {
+	lines := strips(next.Bounds(), e.size, e.dir)
+
+	rs := make([]image.Rectangle, 0, len(lines))
+	for pass := 0; pass < 2; pass++ {
+		for i := pass; i < len(lines); i += 2 {
+			rs = append(rs, lines[i])
+		}
+	}
+
+	return emit(prev, next, rs, e.effectConfig), nil
+}
@@ -1,6 +1,14 @@
 package mixer
 
-import "image"
+import (
+	"image"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
 
 type Write struct {
 	At  image.Point
@@ -14,5 +22,178 @@ type Image interface {
 
 type Effect interface {
 	Name() string
-	Process(img Image) (<-chan Write, error)
+	// Process returns the writes turning prev into next on the screen, prev is nil if unknown
+	Process(prev, next Image) (<-chan Write, error)
+}
+
+// timedEffect is an effect taking about Duration, the drawer paces its writes
+type timedEffect interface {
+	Duration() time.Duration
+}
+
+var effects = map[string]func(opts ...EffectOption) Effect{
+	"block":     EffectBlock,
+	"wipe":      EffectWipe,
+	"blinds":    EffectBlinds,
+	"scanline":  EffectScanline,
+	"spiral":    EffectSpiral,
+	"checker":   EffectChecker,
+	"crossfade": EffectCrossfade,
+}
+
+// Effects returns the sorted names of effects
+func Effects() []string {
+	names := make([]string, 0, len(effects))
+	for name := range effects {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ParseEffect creates an effect by name[:key=value,...], keys are size, speed, dir, steps,
+// order and duration, e.g. wipe:dir=up,duration=2s, block:order=importance or crossfade:steps=6
+func ParseEffect(spec string) (Effect, error) {
+	name, args, _ := strings.Cut(spec, ":")
+
+	fn, ok := effects[name]
+	if !ok {
+		return nil, errors.Errorf("unknown effect %q, supported %s", name, strings.Join(Effects(), ", "))
+	}
+
+	var opts []EffectOption
+	for _, arg := range strings.Split(args, ",") {
+		if arg == "" {
+			continue
+		}
+
+		key, value, _ := strings.Cut(arg, "=")
+		switch key {
+		case "size":
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				return nil, errors.Wrapf(err, "invalid %s", key)
+			}
+			opts = append(opts, WithBlockSize(n))
+		case "steps":
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				return nil, errors.Wrapf(err, "invalid %s", key)
+			}
+			opts = append(opts, WithSteps(n))
+		case "speed":
+			d, err := time.ParseDuration(value)
+			if err != nil {
+				return nil, errors.Wrapf(err, "invalid %s", key)
+			}
+			opts = append(opts, WithSpeed(d))
+		case "duration":
+			d, err := time.ParseDuration(value)
+			if err != nil {
+				return nil, errors.Wrapf(err, "invalid %s", key)
+			}
+			opts = append(opts, WithDuration(d))
+		case "dir":
+			dir, ok := directions[value]
+			if !ok {
+				return nil, errors.Errorf("invalid dir %q, supported right, left, down and up", value)
+			}
+			opts = append(opts, WithDirection(dir))
+		case "order":
+			if value != "importance" {
+				return nil, errors.Errorf("invalid order %q, supported importance", value)
+			}
+			opts = append(opts, WithImportance())
+		default:
+			return nil, errors.Errorf("unknown effect option %q", key)
+		}
+	}
+
+	return fn(opts...), nil
+}
+
+// emit writes the regions of next in order, regions out of the bounds are clipped and
+// the ones same as in prev are skipped
+func emit(prev, next Image, rects []image.Rectangle, c effectConfig) <-chan Write {
+	wc := make(chan Write)
+
+	go func() {
+		defer close(wc)
+
+		b := next.Bounds()
+		from, to := asRGBA(prev), asRGBA(next)
+
+		var ws []Write
+		var scores []int
+		for _, r := range rects {
+			r = r.Intersect(b)
+			if r.Empty() {
+				continue
+			}
+
+			score := 1
+			if from != nil && c.importance {
+				score = tileDistance(from, to, r.Sub(b.Min))
+			} else if from != nil && !tileChanged(from, to, r.Sub(b.Min)) {
+				score = 0
+			}
+			if score == 0 {
+				continue
+			}
+
+			ws = append(ws, Write{At: r.Min.Sub(b.Min), Img: next.SubImage(r)})
+			scores = append(scores, score)
+		}
+
+		if c.importance {
+			sort.Stable(byScore{ws, scores})
+		}
+
+		for i, w := range ws {
+			if i > 0 {
+				c.pause()
+			}
+			wc <- w
+		}
+	}()
+
+	return wc
+}
+
+// byScore sorts the writes with the highest score first
+type byScore struct {
+	ws     []Write
+	scores []int
+}
+
+func (s byScore) Len() int           { return len(s.ws) }
+func (s byScore) Less(i, j int) bool { return s.scores[i] > s.scores[j] }
+func (s byScore) Swap(i, j int) {
+	s.ws[i], s.ws[j] = s.ws[j], s.ws[i]
+	s.scores[i], s.scores[j] = s.scores[j], s.scores[i]
+}
+
+// strips cuts the bounds into strips of size along the direction
+func strips(b image.Rectangle, size int, dir Direction) []image.Rectangle {
+	var rs []image.Rectangle
+	if dir.horizontal() {
+		for x := b.Min.X; x < b.Max.X; x += size {
+			rs = append(rs, image.Rect(x, b.Min.Y, x+size, b.Max.Y))
+		}
+	} else {
+		for y := b.Min.Y; y < b.Max.Y; y += size {
+			rs = append(rs, image.Rect(b.Min.X, y, b.Max.X, y+size))
+		}
+	}
+
+	if dir.reverse() {
+		reverse(rs)
+	}
+	return rs
+}
+
+func reverse(rs []image.Rectangle) {
+	for i, j := 0, len(rs)-1; i < j; i, j = i+1, j-1 {
+		rs[i], rs[j] = rs[j], rs[i]
+	}
 }
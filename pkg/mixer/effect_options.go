@@ -0,0 +1,109 @@
+package mixer
+
+import (
+	"time"
+)
+
+// Direction is where a transition goes, spiral goes inwards for ToRight and ToBottom
+type Direction int
+
+const (
+	ToRight Direction = iota
+	ToLeft
+	ToBottom
+	ToTop
+)
+
+var directions = map[string]Direction{
+	"right": ToRight,
+	"left":  ToLeft,
+	"down":  ToBottom,
+	"up":    ToTop,
+}
+
+func (d Direction) horizontal() bool {
+	return d == ToRight || d == ToLeft
+}
+
+func (d Direction) reverse() bool {
+	return d == ToLeft || d == ToTop
+}
+
+type EffectOption func(c *effectConfig)
+
+type effectConfig struct {
+	size       int
+	sized      bool
+	delay      time.Duration
+	dir        Direction
+	steps      int
+	importance bool
+	duration   time.Duration
+}
+
+// Duration is how long the transition should take, 0 means as fast as the device goes
+func (c effectConfig) Duration() time.Duration {
+	return c.duration
+}
+
+// pause waits the speed delay, timed effects are paced by the drawer instead
+func (c effectConfig) pause() {
+	if c.delay > 0 && c.duration <= 0 {
+		time.Sleep(c.delay)
+	}
+}
+
+func newEffectConfig(size int, opts []EffectOption) effectConfig {
+	c := effectConfig{size: size, steps: 4}
+	for _, opt := range opts {
+		opt(&c)
+	}
+	if c.size < 1 {
+		c.size = 1
+	}
+	if c.steps < 1 {
+		c.steps = 1
+	}
+	return c
+}
+
+// WithBlockSize sets the size in pixels of blocks, strips or slats
+func WithBlockSize(size int) EffectOption {
+	return func(c *effectConfig) {
+		c.size, c.sized = size, true
+	}
+}
+
+// WithSpeed waits delay between writes, the transfer itself is the limit by default
+func WithSpeed(delay time.Duration) EffectOption {
+	return func(c *effectConfig) {
+		c.delay = delay
+	}
+}
+
+func WithDirection(dir Direction) EffectOption {
+	return func(c *effectConfig) {
+		c.dir = dir
+	}
+}
+
+// WithSteps sets the intermediate frames of fading effects
+func WithSteps(steps int) EffectOption {
+	return func(c *effectConfig) {
+		c.steps = steps
+	}
+}
+
+// WithImportance writes the most changed regions first instead of the effect order
+func WithImportance() EffectOption {
+	return func(c *effectConfig) {
+		c.importance = true
+	}
+}
+
+// WithDuration makes the drawer pace the writes to take about d in total, replacing the speed
+func WithDuration(d time.Duration) EffectOption {
+	return func(c *effectConfig) {
+		c.duration = d
+	}
+}
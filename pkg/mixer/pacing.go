@@ -0,0 +1,80 @@
+package mixer
+
+import (
+	"context"
+	"image"
+	"image/draw"
+	"time"
+)
+
+// drawPaced spreads the writes evenly over dur. When the device is behind, the writes
+// already due are merged into one, so the transition still ends in time on slow links.
+func (d *Drawer) drawPaced(ctx context.Context, wc <-chan Write, prev, next *image.RGBA, dur time.Duration) error {
+	var ws []Write
+	for w := range wc {
+		ws = append(ws, w)
+	}
+	if len(ws) == 0 {
+		return nil
+	}
+
+	// what the screen shows during the transition, unknown parts are taken from next
+	shown := clone(next)
+	if prev != nil {
+		shown = clone(prev)
+	}
+
+	n := len(ws)
+	start := time.Now()
+	due := func(i int) time.Time {
+		return start.Add(dur * time.Duration(i) / time.Duration(n))
+	}
+
+	for i := 0; i < n; {
+		if err := sleepUntil(ctx, due(i)); err != nil {
+			return err
+		}
+
+		j := i + 1
+		for j < n && !time.Now().Before(due(j)) {
+			j++
+		}
+
+		var dirty image.Rectangle
+		for _, w := range ws[i:j] {
+			b := w.Img.Bounds()
+			r := b.Sub(b.Min).Add(w.At)
+			draw.Draw(shown, r, w.Img, b.Min, draw.Src)
+			dirty = dirty.Union(r)
+		}
+
+		img := ws[i].Img
+		if j > i+1 {
+			img = shown.SubImage(dirty)
+		}
+
+		if err := d.dev.DrawBitmapContext(ctx, uint16(dirty.Min.X), uint16(dirty.Min.Y), img); err != nil {
+			return err
+		}
+		i = j
+	}
+
+	return nil
+}
+
+func sleepUntil(ctx context.Context, t time.Time) error {
+	wait := time.Until(t)
+	if wait <= 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,192 @@
+package mixer
+
+import (
+	"context"
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"sort"
+	"sync"
+)
+
+// NewScene composes layers into frames of size drawn by d, the layers are blended
+// in z order over black and only the regions touched by changed layers are composed again.
+func NewScene(d *Drawer, size image.Point) *Scene {
+	return &Scene{
+		d:     d,
+		frame: black(size),
+		dirty: image.Rectangle{Max: size},
+	}
+}
+
+type Scene struct {
+	l      sync.Mutex
+	d      *Drawer
+	layers []*Layer
+	seq    int
+	frame  *image.RGBA
+	dirty  image.Rectangle
+}
+
+// Layer returns the layer named name, it is created empty at z if missing.
+// Layers with a higher z are drawn above, equal ones in creation order.
+func (s *Scene) Layer(name string, z int) *Layer {
+	s.l.Lock()
+	defer s.l.Unlock()
+
+	for _, l := range s.layers {
+		if l.name == name {
+			return l
+		}
+	}
+
+	s.seq++
+	l := &Layer{s: s, name: name, z: z, seq: s.seq, alpha: 0xFF}
+	s.layers = append(s.layers, l)
+	sort.SliceStable(s.layers, func(i, j int) bool {
+		if s.layers[i].z != s.layers[j].z {
+			return s.layers[i].z < s.layers[j].z
+		}
+		return s.layers[i].seq < s.layers[j].seq
+	})
+	return l
+}
+
+func (s *Scene) Remove(name string) {
+	s.l.Lock()
+	defer s.l.Unlock()
+
+	for i, l := range s.layers {
+		if l.name == name {
+			s.invalidate(l.rect())
+			s.layers = append(s.layers[:i], s.layers[i+1:]...)
+			return
+		}
+	}
+}
+
+func (s *Scene) Render() error {
+	return s.RenderContext(context.Background())
+}
+
+// RenderContext composes the changed regions and draws them, nothing is sent if no layer changed
+func (s *Scene) RenderContext(ctx context.Context) error {
+	s.l.Lock()
+	defer s.l.Unlock()
+
+	if s.dirty.Empty() {
+		return nil
+	}
+
+	r := s.dirty
+	draw.Draw(s.frame, r, image.Black, image.Point{}, draw.Src)
+	for _, l := range s.layers {
+		if !l.visible() {
+			continue
+		}
+
+		lr := l.rect().Intersect(r)
+		if lr.Empty() {
+			continue
+		}
+
+		sp := lr.Min.Sub(l.at).Add(l.img.Bounds().Min)
+		if l.alpha == 0xFF {
+			draw.Draw(s.frame, lr, l.img, sp, draw.Over)
+		} else {
+			draw.DrawMask(s.frame, lr, l.img, sp, image.NewUniform(color.Alpha{A: l.alpha}), image.Point{}, draw.Over)
+		}
+	}
+
+	// the drawer keeps the frame it draws, so it gets a copy
+	if err := s.d.draw(ctx, clone(s.frame)); err != nil {
+		return err
+	}
+
+	s.dirty = image.Rectangle{}
+	return nil
+}
+
+func (s *Scene) invalidate(r image.Rectangle) {
+	s.dirty = s.dirty.Union(r.Intersect(s.frame.Bounds()))
+}
+
+// Layer is an image placed on a scene, changes are drawn by the next render
+type Layer struct {
+	s      *Scene
+	name   string
+	z      int
+	seq    int
+	img    image.Image
+	at     image.Point
+	alpha  uint8
+	hidden bool
+}
+
+func (l *Layer) Name() string {
+	return l.name
+}
+
+// SetImage replaces the content, img is copied so it could be reused by the caller
+func (l *Layer) SetImage(img image.Image) *Layer {
+	return l.update(func() {
+		if img == nil {
+			l.img = nil
+		} else {
+			l.img = snapshot(img)
+		}
+	})
+}
+
+// Move places the top left of the layer at the position of the scene
+func (l *Layer) Move(at image.Point) *Layer {
+	return l.update(func() {
+		l.at = at
+	})
+}
+
+// SetOpacity blends the layer with opacity in 0-1, 1 by default
+func (l *Layer) SetOpacity(opacity float64) *Layer {
+	return l.update(func() {
+		l.alpha = uint8(math.Round(math.Max(0, math.Min(1, opacity)) * 0xFF))
+	})
+}
+
+func (l *Layer) Show() *Layer {
+	return l.SetVisible(true)
+}
+
+func (l *Layer) Hide() *Layer {
+	return l.SetVisible(false)
+}
+
+func (l *Layer) SetVisible(visible bool) *Layer {
+	return l.update(func() {
+		l.hidden = !visible
+	})
+}
+
+// update applies fn and marks the area covered before and after as changed
+func (l *Layer) update(fn func()) *Layer {
+	l.s.l.Lock()
+	defer l.s.l.Unlock()
+
+	before := l.rect()
+	fn()
+	l.s.invalidate(before.Union(l.rect()))
+	return l
+}
+
+// rect is the area of the scene the layer covers, empty when it draws nothing
+func (l *Layer) rect() image.Rectangle {
+	if !l.visible() {
+		return image.Rectangle{}
+	}
+	b := l.img.Bounds()
+	return b.Sub(b.Min).Add(l.at)
+}
+
+func (l *Layer) visible() bool {
+	return !l.hidden && l.img != nil && l.alpha > 0
+}
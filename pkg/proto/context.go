@@ -0,0 +1,188 @@
+package proto
+
+import (
+	"context"
+	"image"
+	"image/color"
+
+	"usbscreen/pkg/bitmap"
+)
+
+// ContextControl is Control bound to a context. Devices check ctx between the
+// commands they send, an adapted Control returns once ctx is done even when hung.
+type ContextControl interface {
+	StartupContext(ctx context.Context) error
+	ShutdownContext(ctx context.Context) error
+	RestartContext(ctx context.Context) error
+
+	SetLightContext(ctx context.Context, light uint8) error
+	SetMirrorContext(ctx context.Context, mirror bool) error
+	SetRotateContext(ctx context.Context, landscape bool, invert bool) error
+
+	DrawBitmapContext(ctx context.Context, posX uint16, posY uint16, image image.Image) error
+	DrawPixelsContext(ctx context.Context, offsetX uint16, offsetY uint16, color color.Color, coordinates []uint8) error
+
+	StateContext(ctx context.Context) (*State, error)
+}
+
+// WithContext returns dev itself if it supports contexts. Otherwise every call runs
+// in background and is abandoned when ctx is done, the next call waits for it to finish.
+// Only calls through the same adapter wait, so the owner of dev adapts it once and
+// passes the result on.
+func WithContext(dev Control) ContextControl {
+	if cc, ok := dev.(ContextControl); ok {
+		return cc
+	}
+	return &contextAdapter{dev: dev, busy: make(chan struct{}, 1)}
+}
+
+// WithoutContext returns a Control calling dev with the background context
+func WithoutContext(dev ContextControl) Control {
+	if c, ok := dev.(Control); ok {
+		return c
+	}
+	return &backgroundAdapter{dev: dev}
+}
+
+type contextAdapter struct {
+	dev  Control
+	busy chan struct{}
+}
+
+func (a *contextAdapter) StartupContext(ctx context.Context) error {
+	return a.run(ctx, a.dev.Startup)
+}
+
+func (a *contextAdapter) ShutdownContext(ctx context.Context) error {
+	return a.run(ctx, a.dev.Shutdown)
+}
+
+func (a *contextAdapter) RestartContext(ctx context.Context) error {
+	return a.run(ctx, a.dev.Restart)
+}
+
+func (a *contextAdapter) SetLightContext(ctx context.Context, light uint8) error {
+	return a.run(ctx, func() error {
+		return a.dev.SetLight(light)
+	})
+}
+
+func (a *contextAdapter) SetMirrorContext(ctx context.Context, mirror bool) error {
+	return a.run(ctx, func() error {
+		return a.dev.SetMirror(mirror)
+	})
+}
+
+func (a *contextAdapter) SetRotateContext(ctx context.Context, landscape bool, invert bool) error {
+	return a.run(ctx, func() error {
+		return a.dev.SetRotate(landscape, invert)
+	})
+}
+
+func (a *contextAdapter) DrawBitmapContext(ctx context.Context, posX uint16, posY uint16, image image.Image) error {
+	if u, ok := a.dev.(BitmapUploader); ok {
+		return a.run(ctx, func() error {
+			return u.DrawBitmapContext(ctx, posX, posY, image)
+		})
+	}
+
+	err := a.run(ctx, func() error {
+		return a.dev.DrawBitmap(posX, posY, image)
+	})
+	if err == nil {
+		size := bitmap.FormatRGB565.Size()
+		if r, ok := a.dev.(FormatReporter); ok {
+			size = r.Format().Size()
+		}
+		total := image.Bounds().Dx() * image.Bounds().Dy() * size
+		ProgressFrom(ctx)(total, total)
+	}
+	return err
+}
+
+func (a *contextAdapter) DrawPixelsContext(ctx context.Context, offsetX uint16, offsetY uint16, color color.Color, coordinates []uint8) error {
+	return a.run(ctx, func() error {
+		return a.dev.DrawPixels(offsetX, offsetY, color, coordinates)
+	})
+}
+
+func (a *contextAdapter) StateContext(ctx context.Context) (*State, error) {
+	var state *State
+	err := a.run(ctx, func() error {
+		var err error
+		state, err = a.dev.State()
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return state, nil
+}
+
+// run calls fn one at a time, it stops waiting when ctx is done but fn keeps
+// holding the device until it returns.
+func (a *contextAdapter) run(ctx context.Context, fn func() error) error {
+	select {
+	case a.busy <- struct{}{}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	if err := ctx.Err(); err != nil {
+		<-a.busy
+		return err
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		defer func() { <-a.busy }()
+		done <- fn()
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+type backgroundAdapter struct {
+	dev ContextControl
+}
+
+func (a *backgroundAdapter) Startup() error {
+	return a.dev.StartupContext(context.Background())
+}
+
+func (a *backgroundAdapter) Shutdown() error {
+	return a.dev.ShutdownContext(context.Background())
+}
+
+func (a *backgroundAdapter) Restart() error {
+	return a.dev.RestartContext(context.Background())
+}
+
+func (a *backgroundAdapter) SetLight(light uint8) error {
+	return a.dev.SetLightContext(context.Background(), light)
+}
+
+func (a *backgroundAdapter) SetMirror(mirror bool) error {
+	return a.dev.SetMirrorContext(context.Background(), mirror)
+}
+
+func (a *backgroundAdapter) SetRotate(landscape bool, invert bool) error {
+	return a.dev.SetRotateContext(context.Background(), landscape, invert)
+}
+
+func (a *backgroundAdapter) DrawBitmap(posX uint16, posY uint16, image image.Image) error {
+	return a.dev.DrawBitmapContext(context.Background(), posX, posY, image)
+}
+
+func (a *backgroundAdapter) DrawPixels(offsetX uint16, offsetY uint16, color color.Color, coordinates []uint8) error {
+	return a.dev.DrawPixelsContext(context.Background(), offsetX, offsetY, color, coordinates)
+}
+
+func (a *backgroundAdapter) State() (*State, error) {
+	return a.dev.StateContext(context.Background())
+}
@@ -0,0 +1,117 @@
+package proto
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+	"go.bug.st/serial"
+)
+
+type PortInfo struct {
+	Name    string
+	IsUSB   bool
+	VID     string
+	PID     string
+	Serial  string
+	Product string
+}
+
+func (p *PortInfo) String() string {
+	if !p.IsUSB {
+		return p.Name
+	}
+	return fmt.Sprintf("%s usb:%s:%s:%s %s", p.Name, p.VID, p.PID, p.Serial, p.Product)
+}
+
+// ListPorts returns all serial ports with USB details if available
+func ListPorts() ([]*PortInfo, error) {
+	ports, err := detailedPorts()
+	if err != nil {
+		// enumerating could be unsupported, plain names are still usable
+		names, err2 := serial.GetPortsList()
+		if err2 != nil {
+			return nil, err
+		}
+
+		ports = make([]*PortInfo, 0, len(names))
+		for _, name := range names {
+			ports = append(ports, &PortInfo{Name: name})
+		}
+	}
+
+	return ports, nil
+}
+
+// Selector picks a port by a name substring or by the USB attributes, empty fields match any
+type Selector struct {
+	Name   string
+	VID    string
+	PID    string
+	Serial string
+}
+
+// ParseSelector accepts a device name substring (ttyACM0) or usb:VID[:PID[:SERIAL]]
+func ParseSelector(s string) Selector {
+	if !strings.HasPrefix(s, "usb:") {
+		return Selector{Name: s}
+	}
+
+	var sel Selector
+	parts := strings.SplitN(strings.TrimPrefix(s, "usb:"), ":", 3)
+	for i, v := range []*string{&sel.VID, &sel.PID, &sel.Serial} {
+		if i < len(parts) {
+			*v = parts[i]
+		}
+	}
+	return sel
+}
+
+func (s Selector) Match(p *PortInfo) bool {
+	if s.Name != "" && !strings.Contains(p.Name, s.Name) {
+		return false
+	}
+
+	if s.VID == "" && s.PID == "" && s.Serial == "" {
+		return true
+	}
+
+	return p.IsUSB &&
+		(s.VID == "" || strings.EqualFold(s.VID, p.VID)) &&
+		(s.PID == "" || strings.EqualFold(s.PID, p.PID)) &&
+		(s.Serial == "" || s.Serial == p.Serial)
+}
+
+// Pick returns the only port matching, a name equal to the selector wins over substrings.
+// Several matches are an error listing them, so panels of the same model need a serial.
+func (s Selector) Pick(ports []*PortInfo) (*PortInfo, error) {
+	var matched []*PortInfo
+	for _, p := range ports {
+		if s.Name != "" && p.Name == s.Name {
+			return p, nil
+		}
+		if s.Match(p) {
+			matched = append(matched, p)
+		}
+	}
+
+	switch len(matched) {
+	case 0:
+		return nil, errors.Errorf("USB port not found by %s", s)
+	case 1:
+		return matched[0], nil
+	}
+
+	names := make([]string, 0, len(matched))
+	for _, p := range matched {
+		names = append(names, p.String())
+	}
+	return nil, errors.Errorf("%d ports matched by %s, select one by usb:VID:PID:SERIAL: %s", len(matched), s, strings.Join(names, ", "))
+}
+
+func (s Selector) String() string {
+	if s.VID == "" && s.PID == "" && s.Serial == "" {
+		return s.Name
+	}
+	return strings.TrimRight(fmt.Sprintf("usb:%s:%s:%s", s.VID, s.PID, s.Serial), ":")
+}
@@ -0,0 +1,25 @@
+//go:build !darwin || cgo
+
+package proto
+
+import "go.bug.st/serial/enumerator"
+
+func detailedPorts() ([]*PortInfo, error) {
+	details, err := enumerator.GetDetailedPortsList()
+	if err != nil {
+		return nil, err
+	}
+
+	ports := make([]*PortInfo, 0, len(details))
+	for _, d := range details {
+		ports = append(ports, &PortInfo{
+			Name:    d.Name,
+			IsUSB:   d.IsUSB,
+			VID:     d.VID,
+			PID:     d.PID,
+			Serial:  d.SerialNumber,
+			Product: d.Product,
+		})
+	}
+	return ports, nil
+}
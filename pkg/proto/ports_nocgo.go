@@ -0,0 +1,10 @@
+//go:build darwin && !cgo
+
+package proto
+
+import "github.com/pkg/errors"
+
+// the enumerator reads the USB details through IOKit, so only plain port names are listed
+func detailedPorts() ([]*PortInfo, error) {
+	return nil, errors.New("USB details need cgo on darwin")
+}
@@ -2,6 +2,9 @@ package proto
 
 import (
 	"image"
+	"image/color"
+
+	"usbscreen/pkg/bitmap"
 )
 
 type Control interface {
@@ -14,5 +17,27 @@ type Control interface {
 	SetRotate(landscape bool, invert bool) error
 
 	DrawBitmap(posX uint16, posY uint16, image image.Image) error
-	// DrawPixels(offsetX uint16, offsetY uint16, color color.Color, coordinates []uint8) error
+	// DrawPixels paints every (x, y) pair in coordinates, relative to the offset, with the same color
+	DrawPixels(offsetX uint16, offsetY uint16, color color.Color, coordinates []uint8) error
+
+	State() (*State, error)
+}
+
+// FormatReporter is a device telling the pixel format it sends to the panel,
+// so the colors could be reduced to what the panel shows before drawing
+type FormatReporter interface {
+	Format() bitmap.Format
+}
+
+// Calibrator is a device correcting the colors of bitmaps before sending them,
+// Calibration returns the profile and the device drawn to, so a caller reducing
+// the colors itself could apply the profile first and draw there directly
+type Calibrator interface {
+	Calibration() (*bitmap.Profile, ContextControl)
+}
+
+// ResetNotifier is a device which could lose its screen content, e.g. a panel reopened after
+// the port dropped. fn is called once that happened, it must not block or call the device.
+type ResetNotifier interface {
+	OnReset(fn func())
 }
@@ -1,25 +1,72 @@
 package proto
 
 import (
-	"strings"
+	"context"
+	"io"
+	"sync"
+	"time"
 
 	"github.com/pkg/errors"
 	"go.bug.st/serial"
 )
 
+var (
+	ErrDisconnected = errors.New("serial disconnected")
+	ErrWriteTimeout = errors.New("serial write timeout")
+)
+
 type Options struct {
 	DTR      bool
 	RTS      bool
 	BaudRate int
 }
 
-func NewSerial(name string) *Serial {
-	return &Serial{name: name}
+type SerialOption func(s *Serial)
+
+// WithTimeout limits each read and write, 0 means blocking
+func WithTimeout(read, write time.Duration) SerialOption {
+	return func(s *Serial) {
+		s.readTimeout = read
+		s.writeTimeout = write
+	}
+}
+
+// WithReconnect sets how many times to reopen a dropped port before giving up, 0 disables
+func WithReconnect(attempts int, wait time.Duration) SerialOption {
+	return func(s *Serial) {
+		s.attempts = attempts
+		s.wait = wait
+	}
+}
+
+// NewSerial takes a port name substring or an usb:VID:PID:SERIAL selector, see ParseSelector
+func NewSerial(name string, opts ...SerialOption) *Serial {
+	s := &Serial{
+		sel:      ParseSelector(name),
+		attempts: 5,
+		wait:     time.Second,
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
 }
 
 type Serial struct {
-	name string
-	port serial.Port
+	l     sync.Mutex
+	sel   Selector
+	opts  *Options
+	port  serial.Port
+	hooks []func(w io.Writer) error
+	// timedOut is set once a write timed out, the port was closed to unblock it
+	timedOut bool
+	// options
+	readTimeout  time.Duration
+	writeTimeout time.Duration
+	attempts     int
+	wait         time.Duration
 }
 
 func (s *Serial) Ports() ([]string, error) {
@@ -27,32 +74,158 @@ func (s *Serial) Ports() ([]string, error) {
 }
 
 func (s *Serial) Open(opts *Options) error {
-	ports, err := s.Ports()
-	if err != nil {
-		return err
+	s.l.Lock()
+	defer s.l.Unlock()
+
+	s.opts, s.timedOut = opts, false
+	return s.open()
+}
+
+// OnReconnect registers fn to restore the device state after the port reopened,
+// fn must write through w which is bound to the new port.
+func (s *Serial) OnReconnect(fn func(w io.Writer) error) {
+	s.l.Lock()
+	defer s.l.Unlock()
+
+	s.hooks = append(s.hooks, fn)
+}
+
+func (s *Serial) Close() error {
+	s.l.Lock()
+	defer s.l.Unlock()
+
+	s.timedOut = false
+	if s.port == nil {
+		return nil
 	}
 
-	var matched string
-	for _, name := range ports {
-		if strings.Contains(name, s.name) {
-			matched = name
+	err := s.port.Close()
+	s.port = nil
+	return err
+}
+
+func (s *Serial) Read(p []byte) (n int, err error) {
+	s.l.Lock()
+	port := s.port
+	s.l.Unlock()
+
+	if port == nil {
+		return 0, ErrDisconnected
+	}
+
+	return port.Read(p)
+}
+
+// replyGap is how long the line stays quiet after a reply, far more than a byte takes
+const replyGap = 20 * time.Millisecond
+
+// ReadWait waits up to timeout for a reply, it reads until p is full or the line
+// stayed quiet for replyGap after the first bytes arrived
+func (s *Serial) ReadWait(p []byte, timeout time.Duration) (n int, err error) {
+	s.l.Lock()
+	defer s.l.Unlock()
+
+	if s.port == nil {
+		return 0, ErrDisconnected
+	}
+
+	if err := s.port.SetReadTimeout(timeout); err != nil {
+		return 0, err
+	}
+	defer func() {
+		restore := serial.NoTimeout
+		if s.readTimeout > 0 {
+			restore = s.readTimeout
+		}
+		if err2 := s.port.SetReadTimeout(restore); err == nil {
+			err = err2
+		}
+	}()
+
+	for n < len(p) {
+		m, err := s.port.Read(p[n:])
+		if err != nil {
+			return n, err
+		}
+		if m == 0 {
 			break
 		}
+		if n == 0 && replyGap < timeout {
+			if err := s.port.SetReadTimeout(replyGap); err != nil {
+				return m, err
+			}
+		}
+		n += m
+	}
+	return n, nil
+}
+
+func (s *Serial) Write(p []byte) (n int, err error) {
+	if err := s.Frame(p); err != nil {
+		return 0, err
 	}
-	if matched == "" {
-		return errors.New("USB port not found")
+	return len(p), nil
+}
+
+// Frame writes all parts as one unit. If the link dropped halfway, the port is
+// reopened, the reconnect hooks are replayed and the whole unit is sent again.
+func (s *Serial) Frame(parts ...[]byte) error {
+	return s.FrameContext(context.Background(), parts...)
+}
+
+// FrameContext is Frame giving up if ctx is done before the unit is written. A unit
+// is never cut short, the device would take the following commands as its payload,
+// so only the write timeout bounds a hung port.
+func (s *Serial) FrameContext(ctx context.Context, parts ...[]byte) error {
+	s.l.Lock()
+	defer s.l.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	// the port closed by a timed out write is reopened even if reconnecting is disabled
+	if s.timedOut {
+		if err := s.reconnect(ctx, 1); err != nil {
+			return errors.Wrap(err, "reopen failed")
+		}
+	}
+
+	err := s.writeParts(parts)
+	if err == nil || s.attempts <= 0 || ctx.Err() != nil {
+		return err
+	}
+
+	s.drop()
+	if err2 := s.reconnect(ctx, s.attempts); err2 != nil {
+		return errors.Wrapf(err2, "reconnect failed after %s", err)
 	}
 
-	port, err := serial.Open(matched, &serial.Mode{BaudRate: opts.BaudRate})
+	return s.writeParts(parts)
+}
+
+func (s *Serial) open() error {
+	if s.opts == nil {
+		return errors.New("serial not opened")
+	}
+
+	ports, err := ListPorts()
 	if err != nil {
 		return err
 	}
 
-	if err := port.SetDTR(opts.DTR); err != nil {
+	matched, err := s.sel.Pick(ports)
+	if err != nil {
 		return err
 	}
 
-	if err := port.SetRTS(opts.RTS); err != nil {
+	port, err := serial.Open(matched.Name, &serial.Mode{BaudRate: s.opts.BaudRate})
+	if err != nil {
+		return err
+	}
+
+	if err := s.setup(port); err != nil {
+		_ = port.Close()
 		return err
 	}
 
@@ -60,14 +233,120 @@ func (s *Serial) Open(opts *Options) error {
 	return nil
 }
 
-func (s *Serial) Close() error {
-	return s.port.Close()
+func (s *Serial) setup(port serial.Port) error {
+	if err := port.SetDTR(s.opts.DTR); err != nil {
+		return err
+	}
+
+	if err := port.SetRTS(s.opts.RTS); err != nil {
+		return err
+	}
+
+	if s.readTimeout > 0 {
+		if err := port.SetReadTimeout(s.readTimeout); err != nil {
+			return err
+		}
+	}
+
+	return nil
 }
 
-func (s *Serial) Read(p []byte) (n int, err error) {
-	return s.port.Read(p)
+func (s *Serial) reconnect(ctx context.Context, attempts int) error {
+	var err error
+	for i := 0; i < attempts; i++ {
+		if i > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(s.wait):
+			}
+		}
+
+		if err = s.open(); err != nil {
+			continue
+		}
+
+		w := portWriter{s: s}
+		for _, fn := range s.hooks {
+			if err = fn(w); err != nil {
+				break
+			}
+		}
+		if err == nil {
+			s.timedOut = false
+			return nil
+		}
+
+		s.drop()
+	}
+	return err
 }
 
-func (s *Serial) Write(p []byte) (n int, err error) {
-	return s.port.Write(p)
+func (s *Serial) drop() {
+	if s.port != nil {
+		_ = s.port.Close()
+		s.port = nil
+	}
+}
+
+func (s *Serial) writeParts(parts [][]byte) error {
+	for _, p := range parts {
+		if err := s.write(p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Serial) write(p []byte) error {
+	port := s.port
+	if port == nil {
+		return ErrDisconnected
+	}
+
+	if s.writeTimeout <= 0 {
+		n, err := port.Write(p)
+		return written(n, len(p), err)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		n, err := port.Write(p)
+		done <- written(n, len(p), err)
+	}()
+
+	timer := time.NewTimer(s.writeTimeout)
+	defer timer.Stop()
+
+	// closing the port also unblocks the pending write
+	select {
+	case err := <-done:
+		return err
+	case <-timer.C:
+		s.drop()
+		s.timedOut = true
+		return ErrWriteTimeout
+	}
+}
+
+func written(n int, want int, err error) error {
+	if err != nil {
+		return err
+	}
+	if n < want {
+		return io.ErrShortWrite
+	}
+	return nil
+}
+
+// portWriter writes to the port directly, it is only valid while the lock is held
+type portWriter struct {
+	s *Serial
+}
+
+func (w portWriter) Write(p []byte) (int, error) {
+	if err := w.s.write(p); err != nil {
+		return 0, err
+	}
+	return len(p), nil
 }
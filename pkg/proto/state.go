@@ -0,0 +1,24 @@
+package proto
+
+import "math"
+
+// State is what the device is showing, Width and Height are after rotation
+type State struct {
+	Powered   bool
+	Light     *uint8 // nil until the light has been set, panels could not report it
+	Mirror    bool
+	Landscape bool
+	Invert    bool
+	Width     int
+	Height    int
+}
+
+// LightLevel converts a light in percent, 100 is the brightest, to the device level where 0 is
+func LightLevel(percent uint8) uint8 {
+	return uint8((1 - float64(percent)/100) * 255)
+}
+
+// LightPercent converts the device light level back to percent
+func LightPercent(level uint8) uint8 {
+	return uint8(math.Round((1 - float64(level)/255) * 100))
+}
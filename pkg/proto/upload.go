@@ -0,0 +1,34 @@
+package proto
+
+import (
+	"context"
+	"image"
+)
+
+// Progress reports how many bitmap bytes were sent of total
+type Progress func(sent, total int)
+
+type progressKey struct{}
+
+// WithProgress attaches fn to ctx, bitmap uploads under ctx report to it
+func WithProgress(ctx context.Context, fn Progress) context.Context {
+	return context.WithValue(ctx, progressKey{}, fn)
+}
+
+// ProgressFrom returns the progress attached to ctx, it is never nil
+func ProgressFrom(ctx context.Context) Progress {
+	if fn, ok := ctx.Value(progressKey{}).(Progress); ok && fn != nil {
+		return fn
+	}
+	return func(sent, total int) {}
+}
+
+// BitmapUploader is implemented by devices able to report and abort a bitmap upload
+type BitmapUploader interface {
+	DrawBitmapContext(ctx context.Context, posX uint16, posY uint16, image image.Image) error
+}
+
+// DrawBitmap draws with ctx, a device not supporting it is adapted for this call only, see WithContext
+func DrawBitmap(ctx context.Context, dev Control, posX uint16, posY uint16, image image.Image) error {
+	return WithContext(dev).DrawBitmapContext(ctx, posX, posY, image)
+}
@@ -0,0 +1,175 @@
+package text
+
+import (
+	"image"
+	"os"
+
+	"github.com/pkg/errors"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/font/sfnt"
+	"golang.org/x/image/math/fixed"
+)
+
+// cjkFonts are looked up when none of the loaded fonts has CJK glyphs, the first found is used
+var cjkFonts = []string{
+	"/usr/share/fonts/opentype/noto/NotoSansCJK-Regular.ttc",
+	"/usr/share/fonts/noto-cjk/NotoSansCJK-Regular.ttc",
+	"/usr/share/fonts/google-noto-cjk/NotoSansCJK-Regular.ttc",
+	"/usr/share/fonts/truetype/wqy/wqy-microhei.ttc",
+	"/usr/share/fonts/wenquanyi/wqy-microhei/wqy-microhei.ttc",
+	"/usr/share/fonts/truetype/droid/DroidSansFallbackFull.ttf",
+	"/System/Library/Fonts/PingFang.ttc",
+	"/System/Library/Fonts/Hiragino Sans GB.ttc",
+	`C:\Windows\Fonts\msyh.ttc`,
+}
+
+// ErrNoCJKFont is returned by LoadFonts along with the fonts if none has CJK glyphs,
+// they are still usable but CJK text renders as missing glyphs
+var ErrNoCJKFont = errors.New("no CJK font found, pass one with CJK glyphs")
+
+// Fonts is a fallback chain, every glyph is taken from the first font having it
+type Fonts struct {
+	fonts []*sfnt.Font
+}
+
+// LoadFonts parses the TTF, OTF or TTC files in order, followed by the embedded Go font
+// and a CJK font of the system if one is installed. All fonts of a collection are used.
+// Without any CJK font, the fonts are returned with ErrNoCJKFont.
+func LoadFonts(files ...string) (*Fonts, error) {
+	fs := &Fonts{}
+	for _, file := range files {
+		fonts, err := parseFile(file)
+		if err != nil {
+			return nil, err
+		}
+		fs.fonts = append(fs.fonts, fonts...)
+	}
+
+	goFont, err := opentype.Parse(goregular.TTF)
+	if err != nil {
+		return nil, err
+	}
+	fs.fonts = append(fs.fonts, goFont)
+
+	if !fs.Has('中') {
+		for _, file := range cjkFonts {
+			if fonts, err := parseFile(file); err == nil {
+				fs.fonts = append(fs.fonts, fonts...)
+				break
+			}
+		}
+		if !fs.Has('中') {
+			return fs, ErrNoCJKFont
+		}
+	}
+
+	return fs, nil
+}
+
+func parseFile(file string) ([]*sfnt.Font, error) {
+	bs, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	// a single font is read as a collection of one
+	c, err := opentype.ParseCollection(bs)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid font %s", file)
+	}
+
+	fonts := make([]*sfnt.Font, 0, c.NumFonts())
+	for i := 0; i < c.NumFonts(); i++ {
+		f, err := c.Font(i)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid font %s", file)
+		}
+		fonts = append(fonts, f)
+	}
+	return fonts, nil
+}
+
+// Has tells if any of the fonts has a glyph for r
+func (fs *Fonts) Has(r rune) bool {
+	var buf sfnt.Buffer
+	for _, f := range fs.fonts {
+		if x, err := f.GlyphIndex(&buf, r); err == nil && x != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Face returns a face of size pixels, it is not safe to use concurrently as every font.Face
+func (fs *Fonts) Face(size float64) (font.Face, error) {
+	f := &fallback{fonts: fs.fonts}
+	for _, ft := range fs.fonts {
+		face, err := opentype.NewFace(ft, &opentype.FaceOptions{Size: size, DPI: 72, Hinting: font.HintingFull})
+		if err != nil {
+			return nil, err
+		}
+		f.faces = append(f.faces, face)
+	}
+	return f, nil
+}
+
+type fallback struct {
+	fonts []*sfnt.Font
+	faces []font.Face
+	buf   sfnt.Buffer
+}
+
+func (f *fallback) pick(r rune) font.Face {
+	for i, ft := range f.fonts {
+		if x, err := ft.GlyphIndex(&f.buf, r); err == nil && x != 0 {
+			return f.faces[i]
+		}
+	}
+	return f.faces[0]
+}
+
+func (f *fallback) Close() error {
+	for _, face := range f.faces {
+		_ = face.Close()
+	}
+	return nil
+}
+
+func (f *fallback) Glyph(dot fixed.Point26_6, r rune) (image.Rectangle, image.Image, image.Point, fixed.Int26_6, bool) {
+	return f.pick(r).Glyph(dot, r)
+}
+
+func (f *fallback) GlyphBounds(r rune) (fixed.Rectangle26_6, fixed.Int26_6, bool) {
+	return f.pick(r).GlyphBounds(r)
+}
+
+func (f *fallback) GlyphAdvance(r rune) (fixed.Int26_6, bool) {
+	return f.pick(r).GlyphAdvance(r)
+}
+
+func (f *fallback) Kern(r0, r1 rune) fixed.Int26_6 {
+	if face := f.pick(r0); face == f.pick(r1) {
+		return face.Kern(r0, r1)
+	}
+	return 0
+}
+
+// Metrics are the largest of all fonts, so lines fit glyphs of any of them
+func (f *fallback) Metrics() font.Metrics {
+	m := f.faces[0].Metrics()
+	for _, face := range f.faces[1:] {
+		fm := face.Metrics()
+		if fm.Height > m.Height {
+			m.Height = fm.Height
+		}
+		if fm.Ascent > m.Ascent {
+			m.Ascent = fm.Ascent
+		}
+		if fm.Descent > m.Descent {
+			m.Descent = fm.Descent
+		}
+	}
+	return m
+}
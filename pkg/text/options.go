@@ -0,0 +1,93 @@
+package text
+
+import (
+	"image"
+	"image/color"
+
+	"github.com/pkg/errors"
+)
+
+type Align int
+
+const (
+	AlignLeft Align = iota
+	AlignCenter
+	AlignRight
+)
+
+func ParseAlign(name string) (Align, error) {
+	switch name {
+	case "", "left":
+		return AlignLeft, nil
+	case "center":
+		return AlignCenter, nil
+	case "right":
+		return AlignRight, nil
+	}
+	return AlignLeft, errors.Errorf("unknown align %q", name)
+}
+
+type Option func(r *Renderer)
+
+// WithSize sets the font size in pixels, 16 by default
+func WithSize(size float64) Option {
+	return func(r *Renderer) {
+		r.size = size
+	}
+}
+
+func WithColor(c color.Color) Option {
+	return func(r *Renderer) {
+		r.color = c
+	}
+}
+
+// WithBackground fills the image, it is transparent by default
+func WithBackground(c color.Color) Option {
+	return func(r *Renderer) {
+		r.background = c
+	}
+}
+
+// WithWidth wraps lines longer than width pixels, the image is then always as wide
+func WithWidth(width int) Option {
+	return func(r *Renderer) {
+		r.width = width
+	}
+}
+
+func WithAlign(align Align) Option {
+	return func(r *Renderer) {
+		r.align = align
+	}
+}
+
+// WithLineSpacing scales the height of lines, 1 by default
+func WithLineSpacing(spacing float64) Option {
+	return func(r *Renderer) {
+		r.spacing = spacing
+	}
+}
+
+// WithPadding adds space around the text, inside the background
+func WithPadding(padding int) Option {
+	return func(r *Renderer) {
+		r.padding = padding
+	}
+}
+
+// WithOutline strokes the glyphs width pixels wide with c
+func WithOutline(width int, c color.Color) Option {
+	return func(r *Renderer) {
+		r.outline = width
+		r.outlineColor = c
+	}
+}
+
+// WithShadow draws the glyphs and their outline again in c, moved by offset
+func WithShadow(offset image.Point, c color.Color) Option {
+	return func(r *Renderer) {
+		r.shadow = offset
+		r.shadowColor = c
+	}
+}
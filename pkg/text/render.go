@@ -0,0 +1,225 @@
+package text
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/math/fixed"
+)
+
+// NewRenderer renders text with fonts, the images could be drawn by mixer.Drawer
+// directly or used as layers of a scene
+func NewRenderer(fonts *Fonts, opts ...Option) (*Renderer, error) {
+	r := &Renderer{
+		size:    16,
+		color:   color.White,
+		spacing: 1,
+	}
+
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	face, err := fonts.Face(r.size)
+	if err != nil {
+		return nil, err
+	}
+	r.face = face
+
+	return r, nil
+}
+
+type Renderer struct {
+	l    sync.Mutex
+	face font.Face
+	// options
+	size         float64
+	color        color.Color
+	background   color.Color
+	width        int
+	align        Align
+	spacing      float64
+	padding      int
+	outline      int
+	outlineColor color.Color
+	shadow       image.Point
+	shadowColor  color.Color
+}
+
+// Render draws s on a new image starting at 0,0, lines are split at \n and wrapped
+// at spaces or between CJK characters if a width is set
+func (r *Renderer) Render(s string) *image.RGBA {
+	r.l.Lock()
+	defer r.l.Unlock()
+
+	var lines []string
+	for _, para := range strings.Split(s, "\n") {
+		lines = append(lines, r.wrap(para)...)
+	}
+
+	widths := make([]int, len(lines))
+	boxW := r.width
+	for i, line := range lines {
+		widths[i] = font.MeasureString(r.face, line).Ceil()
+		if r.width <= 0 && widths[i] > boxW {
+			boxW = widths[i]
+		}
+	}
+
+	m := r.face.Metrics()
+	lineH := int(math.Ceil(float64(m.Height) / 64 * r.spacing))
+	// the last line keeps its full height so descenders are not cut
+	boxH := lineH*(len(lines)-1) + (m.Ascent + m.Descent).Ceil()
+
+	pad := r.padding + r.outline
+	sx, sy := abs(r.shadow.X), abs(r.shadow.Y)
+	img := image.NewRGBA(image.Rect(0, 0, boxW+pad*2+sx, boxH+pad*2+sy))
+	if r.background != nil {
+		draw.Draw(img, img.Bounds(), image.NewUniform(r.background), image.Point{}, draw.Src)
+	}
+
+	// top left of the text box, shadows going up or left move the text away from the edge
+	origin := image.Pt(pad, pad)
+	if r.shadow.X < 0 {
+		origin.X += sx
+	}
+	if r.shadow.Y < 0 {
+		origin.Y += sy
+	}
+
+	mask := image.NewAlpha(img.Bounds())
+	d := &font.Drawer{Dst: mask, Src: image.Opaque, Face: r.face}
+	for i, line := range lines {
+		x := origin.X
+		switch r.align {
+		case AlignCenter:
+			x += (boxW - widths[i]) / 2
+		case AlignRight:
+			x += boxW - widths[i]
+		}
+		d.Dot = fixed.P(x, origin.Y+i*lineH+m.Ascent.Ceil())
+		d.DrawString(line)
+	}
+
+	stroke := mask
+	if r.outline > 0 {
+		stroke = dilate(mask, r.outline)
+	}
+
+	if r.shadowColor != nil && r.shadow != (image.Point{}) {
+		draw.DrawMask(img, img.Bounds(), image.NewUniform(r.shadowColor), image.Point{}, stroke, r.shadow.Mul(-1), draw.Over)
+	}
+	if r.outline > 0 && r.outlineColor != nil {
+		draw.DrawMask(img, img.Bounds(), image.NewUniform(r.outlineColor), image.Point{}, stroke, image.Point{}, draw.Over)
+	}
+	draw.DrawMask(img, img.Bounds(), image.NewUniform(r.color), image.Point{}, mask, image.Point{}, draw.Over)
+
+	return img
+}
+
+// wrap splits a paragraph into lines fitting the width, words longer than it are broken anywhere
+func (r *Renderer) wrap(para string) []string {
+	if r.width <= 0 {
+		return []string{para}
+	}
+
+	var lines []string
+	var line string
+	for _, seg := range segments(para) {
+		if font.MeasureString(r.face, line+seg).Ceil() <= r.width {
+			line += seg
+			continue
+		}
+
+		if strings.TrimSpace(line) != "" {
+			lines = append(lines, strings.TrimRight(line, " "))
+			line = ""
+		}
+		if strings.TrimSpace(seg) == "" {
+			continue
+		}
+
+		for _, c := range seg {
+			if line != "" && font.MeasureString(r.face, line+string(c)).Ceil() > r.width {
+				lines = append(lines, line)
+				line = ""
+			}
+			line += string(c)
+		}
+	}
+
+	return append(lines, strings.TrimRight(line, " "))
+}
+
+// segments splits s into words, runs of spaces and single CJK characters, where lines could break
+func segments(s string) []string {
+	var segs []string
+	for s != "" {
+		c, size := utf8.DecodeRuneInString(s)
+		n := size
+		if !isCJK(c) {
+			space := c == ' '
+			for n < len(s) {
+				c2, size2 := utf8.DecodeRuneInString(s[n:])
+				if isCJK(c2) || (c2 == ' ') != space {
+					break
+				}
+				n += size2
+			}
+		}
+		segs = append(segs, s[:n])
+		s = s[n:]
+	}
+	return segs
+}
+
+func isCJK(c rune) bool {
+	return unicode.In(c, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul)
+}
+
+// dilate grows the mask by radius pixels, the maximum over a disc around every pixel
+func dilate(mask *image.Alpha, radius int) *image.Alpha {
+	var offsets []image.Point
+	for dy := -radius; dy <= radius; dy++ {
+		for dx := -radius; dx <= radius; dx++ {
+			if dx*dx+dy*dy <= radius*radius+radius {
+				offsets = append(offsets, image.Pt(dx, dy))
+			}
+		}
+	}
+
+	b := mask.Bounds()
+	out := image.NewAlpha(b)
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			a := mask.Pix[mask.PixOffset(x, y)]
+			if a == 0 {
+				continue
+			}
+			for _, o := range offsets {
+				p := image.Pt(x+o.X, y+o.Y)
+				if !p.In(b) {
+					continue
+				}
+				if i := out.PixOffset(p.X, p.Y); out.Pix[i] < a {
+					out.Pix[i] = a
+				}
+			}
+		}
+	}
+	return out
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}